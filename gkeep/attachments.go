@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"mime"
+	"os"
+	"path"
+	"strings"
+)
+
+// attachmentTypes gives the MIME type of the file kinds Keep exports:
+// photos, drawings (exported as PNG) and voice recordings.
+var attachmentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".3gp":  "audio/3gpp",
+	".3gpp": "audio/3gpp",
+	".amr":  "audio/amr",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".wav":  "audio/wav",
+}
+
+// mimeAliases maps the unregistered types Keep sometimes writes in
+// "mimetype" to their registered names.
+var mimeAliases = map[string]string{
+	"audio/3gp": "audio/3gpp",
+	"audio/m4a": "audio/mp4",
+	"image/jpg": "image/jpeg",
+}
+
+// attachmentType returns the MIME type to upload a Keep attachment with:
+// the exported mimetype when it is specific, or else one derived from the
+// file extension.
+func attachmentType(att KeepAttachment) string {
+	t := strings.ToLower(strings.TrimSpace(att.MimeType))
+	if alias, ok := mimeAliases[t]; ok {
+		t = alias
+	}
+	if t != "" && t != "application/octet-stream" {
+		return t
+	}
+	ext := strings.ToLower(path.Ext(att.FilePath))
+	if t, ok := attachmentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func isAudio(att KeepAttachment) bool {
+	return strings.HasPrefix(attachmentType(att), "audio/")
+}
+
+// readAttachment reads an attachment from the export. Takeout sometimes
+// names a file .jpg while the note JSON says .jpeg, or the other way round,
+// so both spellings are tried.
+func (im *importer) readAttachment(filePath string) ([]byte, error) {
+	data, err := im.readFile(filePath)
+	if !errors.Is(err, os.ErrNotExist) {
+		return data, err
+	}
+	ext := path.Ext(filePath)
+	var alt string
+	switch strings.ToLower(ext) {
+	case ".jpeg":
+		alt = strings.TrimSuffix(filePath, ext) + ".jpg"
+	case ".jpg":
+		alt = strings.TrimSuffix(filePath, ext) + ".jpeg"
+	default:
+		return nil, err
+	}
+	if altData, altErr := im.readFile(alt); altErr == nil {
+		return altData, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,74 @@
+package main
+
+import "strings"
+
+// checklistBody renders Keep list items as a Markdown task list.
+//
+// An item whose superListItemId names another item of the list is a sub-item
+// and is indented two spaces per level under its parent. Like Keep, the
+// unchecked top-level items (with their sub-items) come first, then a blank
+// line, then the checked ones. Every item stays on its own line, so the
+// server's toggle_checklist_item line indexing applies to nested items too.
+func checklistBody(items []KeepListItem) string {
+	children := make(map[string][]int)
+	isChild := make([]bool, len(items))
+	ids := make(map[string]bool)
+	for _, item := range items {
+		if item.ID != "" {
+			ids[item.ID] = true
+		}
+	}
+	for i, item := range items {
+		if p := item.SuperListItemID; p != "" && p != item.ID && ids[p] {
+			children[p] = append(children[p], i)
+			isChild[i] = true
+		}
+	}
+
+	visited := make([]bool, len(items))
+	var walk func(lines []string, i, depth int) []string
+	walk = func(lines []string, i, depth int) []string {
+		if visited[i] {
+			return lines
+		}
+		visited[i] = true
+		item := items[i]
+		if item.Text != "" {
+			box := "- [ ] "
+			if item.IsChecked {
+				box = "- [x] "
+			}
+			lines = append(lines, strings.Repeat("  ", depth)+box+item.Text)
+			depth++
+		}
+		if item.ID != "" {
+			for _, c := range children[item.ID] {
+				lines = walk(lines, c, depth)
+			}
+		}
+		return lines
+	}
+
+	var unchecked, checked []string
+	for i, item := range items {
+		if isChild[i] {
+			continue
+		}
+		if item.IsChecked {
+			checked = walk(checked, i, 0)
+		} else {
+			unchecked = walk(unchecked, i, 0)
+		}
+	}
+	// Sub-items whose parent chain loops back on itself were never reached.
+	for i := range items {
+		if !visited[i] {
+			unchecked = walk(unchecked, i, 0)
+		}
+	}
+
+	if len(unchecked) > 0 && len(checked) > 0 {
+		unchecked = append(unchecked, "")
+	}
+	return strings.Join(append(unchecked, checked...), "\n")
+}
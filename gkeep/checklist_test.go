@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestChecklistBody renders each testdata/checklist_*.json note and compares
+// the result with the .md file of the same name. Run with -update to rewrite
+// them.
+func TestChecklistBody(t *testing.T) {
+	files, err := filepath.Glob("testdata/checklist_*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata/checklist_*.json files")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		t.Run(name, func(t *testing.T) {
+			note := readKeepNote(t, file)
+			got := checklistBody(note.ListContent)
+
+			golden := strings.TrimSuffix(file, ".json") + ".md"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("checklistBody(%s):\n%s\nwant:\n%s", file, got, want)
+			}
+			checkEveryItemOnce(t, note.ListContent, got)
+		})
+	}
+}
+
+// taskLine matches a line that the server's toggle_checklist_item toggles.
+var taskLine = regexp.MustCompile(`^\s*- \[([ xX])\] (.*)$`)
+
+// checkEveryItemOnce checks that each item with text has its own task line,
+// with its own checked state, and that every other line is blank.
+func checkEveryItemOnce(t *testing.T, items []KeepListItem, body string) {
+	t.Helper()
+	seen := make(map[string]int)
+	for i, line := range strings.Split(body, "\n") {
+		m := taskLine.FindStringSubmatch(line)
+		if m == nil {
+			if line != "" {
+				t.Errorf("line %d %q is not a task", i, line)
+			}
+			continue
+		}
+		seen[m[2]]++
+		for _, item := range items {
+			if item.Text == m[2] && item.IsChecked != (m[1] != " ") {
+				t.Errorf("line %d %q: checked = %v, want %v", i, line, !item.IsChecked, item.IsChecked)
+			}
+		}
+	}
+	for _, item := range items {
+		if item.Text != "" && seen[item.Text] != 1 {
+			t.Errorf("item %q is on %d lines, want 1", item.Text, seen[item.Text])
+		}
+	}
+}
+
+// TestChecklistLineIndices pins the line index at which the server finds each
+// item of a nested list, as toggle_checklist_item counts them.
+func TestChecklistLineIndices(t *testing.T) {
+	note := readKeepNote(t, "testdata/checklist_nested.json")
+	lines := strings.Split(checklistBody(note.ListContent), "\n")
+	want := map[string]int{
+		"Pack":          0,
+		"Socks":         1,
+		"Charger":       2,
+		"USB-C cable":   3,
+		"Water plants":  4,
+		"Book hotel":    6,
+		"Confirm dates": 7,
+	}
+	for text, i := range want {
+		if i >= len(lines) {
+			t.Errorf("%q: line %d is past the end of the body", text, i)
+			continue
+		}
+		m := taskLine.FindStringSubmatch(lines[i])
+		if m == nil || m[2] != text {
+			t.Errorf("line %d = %q, want the task %q", i, lines[i], text)
+		}
+	}
+}
+
+func readKeepNote(t *testing.T, file string) KeepNote {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var note KeepNote
+	if err := json.Unmarshal(data, &note); err != nil {
+		t.Fatalf("parse %s: %v", file, err)
+	}
+	return note
+}
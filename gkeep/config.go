@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mbright/notesapi/provenance"
+)
+
+// Config holds the settings that can come from the command line, the
+// environment or a config file. For each one a flag wins over its
+// environment variable, which wins over the config file, which wins over
+// the default.
+type Config struct {
+	NotesURL    string `json:"notes_url"`
+	Takeout     string `json:"takeout"`
+	Credentials string `json:"credentials"`
+	Rate        string `json:"rate"`
+	Journal     string `json:"journal"`
+	Workers     int    `json:"workers"`
+	// ColorTags turns each note's Keep color into a tag. ColorTable
+	// overrides the default keep-color/<color> tag name per color.
+	ColorTags  bool              `json:"color_tags"`
+	ColorTable map[string]string `json:"color_table"`
+	// Dedup is the dedup strategy, "title-created" or "content".
+	// NearDuplicates says what to do with a note that the content strategy
+	// finds to share only its title, only its body, or both but not its
+	// attachments with an existing note: "create", "skip" or "update".
+	Dedup          string `json:"dedup"`
+	NearDuplicates string `json:"near_duplicates"`
+	// Provenance is how each note records where it came from: "none",
+	// "trailer" or "front-matter".
+	Provenance string `json:"provenance"`
+}
+
+const defaultConfigFile = "gkeep.json"
+
+var defaultConfig = Config{
+	Takeout:        "Takeout",
+	Credentials:    "credentials",
+	Rate:           "300/5m",
+	Journal:        "import-journal.json",
+	Workers:        1,
+	Dedup:          dedupTitleCreated,
+	NearDuplicates: nearDupCreate,
+	Provenance:     provenance.None,
+}
+
+// configEnv names the environment variable for each setting that has one.
+var configEnv = map[string]string{
+	"notes-url":   "NOTES_URL",
+	"takeout":     "GKEEP_TAKEOUT",
+	"credentials": "GKEEP_CREDENTIALS",
+	"rate":        "GKEEP_RATE",
+	"color-tags":  "GKEEP_COLOR_TAGS",
+	"provenance":  "GKEEP_PROVENANCE",
+}
+
+// registerConfigFlags defines a flag for every Config field on fs. Their
+// values are read back by resolveConfig, and only if set explicitly.
+func registerConfigFlags(fs *flag.FlagSet) {
+	fs.String("notes-url", "", "Base URL of the Notes instance (env NOTES_URL)")
+	fs.String("takeout", defaultConfig.Takeout, "Google Takeout export: an extracted folder, its Keep folder, or a .zip/.tgz archive (env GKEEP_TAKEOUT)")
+	fs.String("credentials", defaultConfig.Credentials, "Plaintext credentials file with user:/password: lines (env GKEEP_CREDENTIALS)")
+	fs.String("rate", defaultConfig.Rate, "Notes API request budget as requests/window, e.g. 300/5m (env GKEEP_RATE)")
+	fs.String("journal", defaultConfig.Journal, "Path of the import progress journal")
+	fs.Int("workers", defaultConfig.Workers, "Number of notes to import in parallel")
+	fs.Bool("color-tags", false, "Tag each note with its Keep color, e.g. keep-color/red (env GKEEP_COLOR_TAGS)")
+	fs.String("dedup", defaultConfig.Dedup, "Dedup strategy: title-created, or content (normalized title, body and attachment names)")
+	fs.String("provenance", defaultConfig.Provenance, "Record each note's origin in its body as a trailer or front-matter (after the list for checklists), and tag it imported/google-keep: none, trailer or front-matter (env GKEEP_PROVENANCE)")
+	fs.String("near-duplicates", defaultConfig.NearDuplicates, "With --dedup content, what to do with near duplicates: create, skip or update")
+}
+
+// resolveConfig layers the config file, the environment and explicitly set
+// flags over the defaults. An empty configPath reads gkeep.json if present.
+func resolveConfig(fs *flag.FlagSet, configPath string) (*Config, error) {
+	cfg := defaultConfig
+
+	explicit := configPath != ""
+	if !explicit {
+		configPath = defaultConfigFile
+	}
+	data, err := os.ReadFile(configPath)
+	switch {
+	case err == nil:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", configPath, err)
+		}
+	case explicit || !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	for name, env := range configEnv {
+		if v := os.Getenv(env); v != "" {
+			if err := cfg.set(name, v); err != nil {
+				return nil, fmt.Errorf("%s: %w", env, err)
+			}
+		}
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		if err := cfg.set(f.Name, f.Value.String()); err != nil && flagErr == nil {
+			flagErr = fmt.Errorf("--%s: %w", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if cfg.Workers < 1 {
+		return nil, fmt.Errorf("workers must be at least 1")
+	}
+	if _, _, err := parseRate(cfg.Rate); err != nil {
+		return nil, err
+	}
+	if cfg.Dedup != dedupTitleCreated && cfg.Dedup != dedupContent {
+		return nil, fmt.Errorf("invalid dedup %q: want title-created or content", cfg.Dedup)
+	}
+	if err := provenance.CheckStyle(cfg.Provenance); err != nil {
+		return nil, err
+	}
+	switch cfg.NearDuplicates {
+	case nearDupCreate, nearDupSkip, nearDupUpdate:
+	default:
+		return nil, fmt.Errorf("invalid near_duplicates %q: want create, skip or update", cfg.NearDuplicates)
+	}
+	cfg.NotesURL = strings.TrimRight(cfg.NotesURL, "/")
+	return &cfg, nil
+}
+
+// set assigns the setting with the given flag name. Names that are not
+// settings are ignored.
+func (c *Config) set(name, value string) error {
+	switch name {
+	case "notes-url":
+		c.NotesURL = value
+	case "takeout":
+		c.Takeout = value
+	case "credentials":
+		c.Credentials = value
+	case "rate":
+		c.Rate = value
+	case "journal":
+		c.Journal = value
+	case "workers":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		c.Workers = n
+	case "color-tags":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		c.ColorTags = b
+	case "dedup":
+		c.Dedup = value
+	case "near-duplicates":
+		c.NearDuplicates = value
+	case "provenance":
+		c.Provenance = value
+	}
+	return nil
+}
+
+// parseRate parses a request budget such as "300/5m".
+func parseRate(s string) (limit int, window time.Duration, err error) {
+	n, w, ok := strings.Cut(s, "/")
+	if ok {
+		limit, err = strconv.Atoi(strings.TrimSpace(n))
+	}
+	if ok && err == nil {
+		window, err = time.ParseDuration(strings.TrimSpace(w))
+	}
+	if !ok || err != nil || limit < 1 || window <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q: want requests/window, e.g. 300/5m", s)
+	}
+	return limit, window, nil
+}
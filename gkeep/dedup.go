@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/provenance"
+)
+
+// Dedup strategies, chosen with --dedup.
+const (
+	// dedupTitleCreated treats notes with the same title and creation second
+	// as duplicates.
+	dedupTitleCreated = "title-created"
+	// dedupContent treats notes with the same normalized title and body, and
+	// the same attachment filenames, as duplicates.
+	dedupContent = "content"
+)
+
+// Near-duplicate policies, chosen with --near-duplicates.
+const (
+	nearDupCreate = "create"
+	nearDupSkip   = "skip"
+	nearDupUpdate = "update"
+)
+
+func dedupKey(title string, createdAt time.Time) string {
+	// Normalize to whole seconds in UTC for consistent comparison
+	return title + "|" + createdAt.UTC().Format("2006-01-02T15:04:05")
+}
+
+// normalizeText lowercases s and collapses all runs of whitespace, so that
+// formatting differences the server may introduce do not matter.
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+func hashText(s string) string {
+	sum := sha256.Sum256([]byte(normalizeText(s)))
+	return hex.EncodeToString(sum[:])
+}
+
+// contentKey hashes a note's normalized title and body.
+func contentKey(title, body string) string {
+	return hashText(title) + hashText(body)
+}
+
+// dedupSet holds the dedup keys of notes that exist on the server or are
+// being imported by this run.
+type dedupSet struct {
+	mu       sync.Mutex
+	strategy string
+	keys     map[string]int // dedup key → note ID, or 0 while this run creates it
+	// Content strategy only: existing notes by ID, and the IDs of notes
+	// sharing a title or a body, for finding near duplicates.
+	notes  map[int]notesapi.Note
+	titles map[string][]int
+	bodies map[string][]int
+	// attachments caches the attachment filenames of existing notes.
+	attachments map[int][]string
+}
+
+// key returns the dedup key of a note under the set's strategy. Bodies are
+// compared without their provenance, which differs from run to run.
+func (d *dedupSet) key(title, body string, createdAt time.Time) string {
+	if d.strategy == dedupContent {
+		return contentKey(title, provenance.Strip(body))
+	}
+	return dedupKey(title, createdAt)
+}
+
+// claim records key and reports whether it was new. A worker that claims a
+// key owns creating that note; a second file with the same key is skipped.
+// If the key was taken, claim also returns the ID of the existing note (0
+// if this run is creating it).
+func (d *dedupSet) claim(key string) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if id, ok := d.keys[key]; ok {
+		return id, false
+	}
+	d.keys[key] = 0
+	return 0, true
+}
+
+// release forgets a claimed key whose note could not be created.
+func (d *dedupSet) release(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.keys, key)
+}
+
+// nearDuplicates returns the existing notes that share either the title
+// (if not empty) or the body of a note, but not both.
+func (d *dedupSet) nearDuplicates(title, body string) []notesapi.Note {
+	if d.strategy != dedupContent {
+		return nil
+	}
+	body = provenance.Strip(body)
+	t, b := hashText(title), hashText(body)
+	var ids []int
+	if normalizeText(title) != "" {
+		ids = append(ids, d.titles[t]...)
+	}
+	if normalizeText(body) != "" {
+		ids = append(ids, d.bodies[b]...)
+	}
+	slices.Sort(ids)
+	var out []notesapi.Note
+	for _, id := range slices.Compact(ids) {
+		n := d.notes[id]
+		if hashText(n.Title) != t || hashText(n.Body) != b {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// sameAttachments reports whether an existing note has exactly the given
+// attachment filenames, listing its attachments once per note.
+func (d *dedupSet) sameAttachments(c *notesapi.Client, id int, atts []KeepAttachment) (bool, error) {
+	d.mu.Lock()
+	names, ok := d.attachments[id]
+	d.mu.Unlock()
+	if !ok {
+		list, err := c.ListAttachments(id)
+		if err != nil {
+			return false, err
+		}
+		names = []string{}
+		for _, a := range list {
+			names = append(names, a.Filename)
+		}
+		slices.Sort(names)
+		d.mu.Lock()
+		d.attachments[id] = names
+		d.mu.Unlock()
+	}
+
+	want := make([]string, len(atts))
+	for i, a := range atts {
+		want[i] = filepath.Base(a.FilePath)
+	}
+	slices.Sort(want)
+	return slices.Equal(names, want), nil
+}
+
+func fetchExistingNotes(c *notesapi.Client, strategy string) (*dedupSet, error) {
+	existing := &dedupSet{
+		strategy:    strategy,
+		keys:        make(map[string]int),
+		notes:       make(map[int]notesapi.Note),
+		titles:      make(map[string][]int),
+		bodies:      make(map[string][]int),
+		attachments: make(map[int][]string),
+	}
+
+	for _, filter := range []string{"", "archived", "trash"} {
+		opts := notesapi.ListNotesOptions{Filter: filter, ListOptions: notesapi.ListOptions{Limit: 100}}
+		for n, err := range c.AllNotes(opts) {
+			if err != nil {
+				return nil, fmt.Errorf("fetch notes (filter=%s): %w", filter, err)
+			}
+			n.Body = provenance.Strip(n.Body)
+			existing.keys[existing.key(n.Title, n.Body, n.CreatedAt)] = n.ID
+			if strategy == dedupContent {
+				existing.notes[n.ID] = n
+				existing.titles[hashText(n.Title)] = append(existing.titles[hashText(n.Title)], n.ID)
+				existing.bodies[hashText(n.Body)] = append(existing.bodies[hashText(n.Body)], n.ID)
+			}
+		}
+	}
+
+	log.Printf("Found %d existing notes for dedup (strategy %s)", len(existing.keys), strategy)
+	return existing, nil
+}
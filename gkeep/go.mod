@@ -1,3 +1,7 @@
 module gkeep-import
 
 go 1.26.0
+
+require github.com/mbright/notesapi v0.0.0
+
+replace github.com/mbright/notesapi => ../notesapi
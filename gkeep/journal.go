@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+
+	"github.com/mbright/notesapi/journal"
+)
+
+// JournalEntry records how far the import of a single Keep note got.
+type JournalEntry struct {
+	NoteID      int             `json:"note_id"`
+	Archived    bool            `json:"archived,omitempty"`
+	Trashed     bool            `json:"trashed,omitempty"`
+	Attachments map[string]bool `json:"attachments,omitempty"` // filePath → uploaded
+	Shares      map[string]bool `json:"shares,omitempty"`      // email → shared or unmatched
+	// Checksum is the SHA-256 of the Keep file the note was last written
+	// from; --update rewrites the note when it changes.
+	Checksum string `json:"checksum,omitempty"`
+	Done     bool   `json:"done"`
+}
+
+func (e *JournalEntry) clone() *JournalEntry {
+	c := *e
+	c.Attachments = maps.Clone(e.Attachments)
+	c.Shares = maps.Clone(e.Shares)
+	return &c
+}
+
+// checksum returns the hex SHA-256 of a Keep file.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Journal checkpoints import progress, keyed by the Keep JSON filename.
+type Journal = journal.Journal[JournalEntry]
+
+// openJournal loads the journal at path, or starts an empty one.
+func openJournal(path string) (*Journal, error) {
+	return journal.Open(path, (*JournalEntry).clone)
+}
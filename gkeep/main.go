@@ -1,47 +1,54 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
-	"net/http"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
-)
 
-const (
-	baseURL        = "https://hippo.chameleon-gopher.ts.net"
-	apiBase        = baseURL + "/api/v1"
-	rateLimit      = 280
-	rateWindow     = 5 * time.Minute
-	keepDir        = "Takeout/Keep"
-	credentialsFile = "credentials"
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/credentials"
+	"github.com/mbright/notesapi/manifest"
+	"github.com/mbright/notesapi/provenance"
 )
 
 // --- Google Keep JSON schema ---
 
 type KeepNote struct {
-	Color                    string           `json:"color"`
-	IsTrashed                bool             `json:"isTrashed"`
-	IsPinned                 bool             `json:"isPinned"`
-	IsArchived               bool             `json:"isArchived"`
-	Title                    string           `json:"title"`
-	TextContent              string           `json:"textContent"`
-	UserEditedTimestampUsec  int64            `json:"userEditedTimestampUsec"`
-	CreatedTimestampUsec     int64            `json:"createdTimestampUsec"`
-	ListContent              []KeepListItem   `json:"listContent"`
-	Annotations              []KeepAnnotation `json:"annotations"`
-	Attachments              []KeepAttachment `json:"attachments"`
+	Color                   string           `json:"color"`
+	IsTrashed               bool             `json:"isTrashed"`
+	IsPinned                bool             `json:"isPinned"`
+	IsArchived              bool             `json:"isArchived"`
+	Title                   string           `json:"title"`
+	TextContent             string           `json:"textContent"`
+	TextContentHTML         string           `json:"textContentHtml"`
+	UserEditedTimestampUsec int64            `json:"userEditedTimestampUsec"`
+	CreatedTimestampUsec    int64            `json:"createdTimestampUsec"`
+	ListContent             []KeepListItem   `json:"listContent"`
+	Annotations             []KeepAnnotation `json:"annotations"`
+	Attachments             []KeepAttachment `json:"attachments"`
+	Labels                  []KeepLabel      `json:"labels"`
+	Sharees                 []KeepSharee     `json:"sharees"`
+	Reminders               []KeepReminder   `json:"reminders"`
 }
 
 type KeepListItem struct {
 	Text      string `json:"text"`
 	IsChecked bool   `json:"isChecked"`
+	// ID and SuperListItemID link a sub-item to its parent item.
+	ID              string `json:"id"`
+	SuperListItemID string `json:"superListItemId"`
 }
 
 type KeepAnnotation struct {
@@ -51,141 +58,71 @@ type KeepAnnotation struct {
 	URL         string `json:"url"`
 }
 
-type KeepAttachment struct {
-	FilePath string `json:"filePath"`
-	MimeType string `json:"mimetype"`
-}
-
-// --- API response types ---
-
-type AuthResponse struct {
-	Token     string `json:"token"`
-	ExpiresAt string `json:"expires_at"`
-}
-
-type NoteResponse struct {
-	ID        int    `json:"id"`
-	Title     string `json:"title"`
-	CreatedAt string `json:"created_at"`
-}
-
-type NotesListResponse struct {
-	Notes      []NoteResponse `json:"notes"`
-	Pagination struct {
-		Page  int `json:"page"`
-		Pages int `json:"pages"`
-		Count int `json:"count"`
-	} `json:"pagination"`
-}
-
-// --- Rate limiter ---
-
-type RateLimiter struct {
-	count     int
-	windowStart time.Time
+type KeepLabel struct {
+	Name string `json:"name"`
 }
 
-func (rl *RateLimiter) Wait() {
-	if rl.windowStart.IsZero() {
-		rl.windowStart = time.Now()
-	}
-	rl.count++
-	if rl.count >= rateLimit {
-		elapsed := time.Since(rl.windowStart)
-		if elapsed < rateWindow {
-			sleep := rateWindow - elapsed
-			log.Printf("Rate limit: sleeping %v", sleep)
-			time.Sleep(sleep)
-		}
-		rl.count = 0
-		rl.windowStart = time.Now()
-	}
+type KeepSharee struct {
+	Email   string `json:"email"`
+	IsOwner bool   `json:"isOwner"`
+	Type    string `json:"type"`
 }
 
-// --- HTTP helpers ---
-
-type Client struct {
-	http  *http.Client
-	token string
-	rl    RateLimiter
+type KeepAttachment struct {
+	FilePath string `json:"filePath"`
+	MimeType string `json:"mimetype"`
 }
 
-func (c *Client) doJSON(method, url string, body any) ([]byte, int, error) {
-	c.rl.Wait()
-
-	var reqBody io.Reader
-	if body != nil {
-		data, err := json.Marshal(body)
-		if err != nil {
-			return nil, 0, fmt.Errorf("marshal: %w", err)
-		}
-		reqBody = bytes.NewReader(data)
-	}
+// --- Core logic ---
 
-	req, err := http.NewRequest(method, url, reqBody)
+// loadCredentials finds the Notes credentials to import with, in order of
+// precedence:
+//
+//  1. the --token flag
+//  2. NOTES_TOKEN, or NOTES_EMAIL and NOTES_PASSWORD
+//  3. a ~/.netrc entry for the Notes host
+//  4. the legacy plaintext credentials file
+//  5. an interactive prompt, with the password hidden
+func loadCredentials(token string, cfg *Config) (credentials.Credentials, error) {
+	if token != "" {
+		return credentials.Credentials{Token: token, Source: "--token"}, nil
+	}
+	if c, ok := credentials.FromEnv(); ok {
+		return c, nil
+	}
+	c, ok, err := credentials.FromNetrc(cfg.NotesURL, "")
 	if err != nil {
-		return nil, 0, err
+		return c, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if ok {
+		return c, nil
 	}
 
-	resp, err := c.http.Do(req)
-	if err != nil {
-		return nil, 0, err
+	email, password, err := parseCredentials(cfg.Credentials)
+	if err == nil {
+		return credentials.Credentials{Email: email, Password: password, Source: cfg.Credentials}, nil
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, resp.StatusCode, err
+	if !errors.Is(err, os.ErrNotExist) {
+		return c, err
 	}
-	return respBody, resp.StatusCode, nil
-}
-
-func (c *Client) uploadFile(url, filePath string) error {
-	c.rl.Wait()
 
-	f, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("open %s: %w", filePath, err)
+	if !credentials.IsTerminal(os.Stdin) {
+		return c, fmt.Errorf("no credentials found; use --token, %s, %s/%s, %s or a %s file",
+			credentials.EnvToken, credentials.EnvEmail, credentials.EnvPassword, credentials.NetrcPath(), cfg.Credentials)
 	}
-	defer f.Close()
-
-	var buf bytes.Buffer
-	w := multipart.NewWriter(&buf)
-	part, err := w.CreateFormFile("files[]", filepath.Base(filePath))
+	fmt.Fprint(os.Stderr, "Notes email: ")
+	email, err = bufio.NewReader(os.Stdin).ReadString('\n')
 	if err != nil {
-		return fmt.Errorf("create form file: %w", err)
-	}
-	if _, err := io.Copy(part, f); err != nil {
-		return fmt.Errorf("copy file: %w", err)
+		return c, fmt.Errorf("read email: %w", err)
 	}
-	w.Close()
-
-	req, err := http.NewRequest("POST", url, &buf)
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err = credentials.ReadPassword(os.Stdin)
 	if err != nil {
-		return err
+		return c, fmt.Errorf("read password: %w", err)
 	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+c.token)
-
-	resp, err := c.http.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed (%d): %s", resp.StatusCode, body)
-	}
-	return nil
+	return credentials.Credentials{Email: strings.TrimSpace(email), Password: password, Source: "prompt"}, nil
 }
 
-// --- Core logic ---
-
 func parseCredentials(path string) (email, password string, err error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -205,23 +142,15 @@ func parseCredentials(path string) (email, password string, err error) {
 	return email, password, nil
 }
 
-func authenticate(c *Client, email, password string) error {
-	body, status, err := c.doJSON("POST", apiBase+"/auth/token", map[string]string{
-		"email":    email,
-		"password": password,
-	})
-	if err != nil {
-		return fmt.Errorf("auth request: %w", err)
-	}
-	if status != http.StatusOK {
-		return fmt.Errorf("auth failed (%d): %s", status, body)
+func authenticate(c *notesapi.Client, creds credentials.Credentials) error {
+	if err := credentials.Login(c, creds); err != nil {
+		return err
 	}
-	var auth AuthResponse
-	if err := json.Unmarshal(body, &auth); err != nil {
-		return fmt.Errorf("auth parse: %w", err)
+	if exp := c.TokenExpiresAt(); !exp.IsZero() {
+		log.Printf("Authenticated as %s (token expires %s)", creds, exp.Format(time.RFC3339))
+	} else {
+		log.Printf("Using %s", creds)
 	}
-	c.token = auth.Token
-	log.Printf("Authenticated (token expires %s)", auth.ExpiresAt)
 	return nil
 }
 
@@ -229,24 +158,32 @@ func usecToTime(usec int64) time.Time {
 	return time.UnixMicro(usec)
 }
 
+// noteText returns the body of a text note: its rich text as Markdown when
+// Keep exported it and it converts cleanly, or else the plain text.
+//
+// Keep stores the automatic transcription of a voice recording as the text
+// of its note, so for notes with a recording the text is put under a
+// Transcription heading to set it apart from anything typed.
+func noteText(note KeepNote) string {
+	text := note.TextContent
+	if note.TextContentHTML != "" {
+		if md, err := htmlToMarkdown(note.TextContentHTML); err == nil {
+			text = md
+		}
+	}
+	if text == "" || !slices.ContainsFunc(note.Attachments, isAudio) {
+		return text
+	}
+	return "### Transcription\n\n" + text
+}
+
 func buildBody(note KeepNote) string {
 	var body string
 
 	if len(note.ListContent) > 0 {
-		var lines []string
-		for _, item := range note.ListContent {
-			if item.Text == "" {
-				continue
-			}
-			if item.IsChecked {
-				lines = append(lines, "- [x] "+item.Text)
-			} else {
-				lines = append(lines, "- [ ] "+item.Text)
-			}
-		}
-		body = strings.Join(lines, "\n")
+		body = checklistBody(note.ListContent)
 	} else {
-		body = note.TextContent
+		body = noteText(note)
 	}
 
 	if len(note.Annotations) > 0 {
@@ -269,187 +206,629 @@ func buildBody(note KeepNote) string {
 	return body
 }
 
-func dedupKey(title, createdAt string) string {
-	// Normalize: parse and re-format to strip milliseconds for consistent comparison
-	t, err := time.Parse(time.RFC3339Nano, createdAt)
-	if err != nil {
-		// Try alternate format with .000Z
-		t, err = time.Parse("2006-01-02T15:04:05.000Z", createdAt)
-		if err != nil {
-			return title + "|" + createdAt
+// importResult indicates the outcome of importing a single note.
+type importResult int
+
+const (
+	resultCreated importResult = iota
+	resultSkipped
+	resultResumed
+	resultUpdated
+)
+
+// importer holds the state shared by every note of an import run.
+type importer struct {
+	client   *notesapi.Client
+	takeout  takeout
+	existing *dedupSet
+	tags     *notesapi.TagSet
+	// colorTags maps Keep colors to tag names, or is nil to ignore colors.
+	colorTags map[string]string
+	// self is the importing account's email, if known; it is never shared
+	// with.
+	self      string
+	unmatched reportSet
+	// nearDups lists, per existing note, the Keep notes that nearly
+	// duplicate it; nearDupPolicy says what to do about them.
+	nearDups      reportSet
+	nearDupPolicy string
+	journal       *Journal
+	// manifest records what the run creates, for rollback.
+	manifest *manifest.Account
+	// provenance is the provenance style; startedAt and importerName are
+	// recorded with it.
+	provenance   string
+	startedAt    time.Time
+	importerName string
+	// update rewrites notes whose Keep file changed since the journal
+	// recorded them; resume finishes notes a previous run left half done.
+	update bool
+	resume bool
+	dryRun bool
+}
+
+// noteParams builds the create request for a Keep note read from source.
+// Reminders, if any, stay at the very top of the body, ahead of front-matter
+// provenance. Notes shows every line of a checklist that is not a task, so
+// for checklist notes both blocks follow the list instead, in the same
+// order.
+func (im *importer) noteParams(source string, note KeepNote) notesapi.NoteParams {
+	body := buildBody(note)
+	checklist := len(note.ListContent) > 0
+	reminders := reminderFrontMatter(note.Reminders)
+	if checklist && reminders != "" {
+		body = strings.TrimRight(body, "\n") + "\n\n" + strings.TrimSuffix(reminders, "\n")
+		reminders = ""
+	}
+	if im.provenance != provenance.None {
+		p := provenance.Provenance{
+			Source:     keepSource,
+			SourceID:   source,
+			ImportedAt: im.startedAt,
+			Importer:   im.importerName,
+		}
+		if checklist {
+			body = provenance.EmbedAfter(body, p, im.provenance)
+		} else {
+			body = provenance.Embed(body, p, im.provenance)
 		}
 	}
-	return title + "|" + t.UTC().Format("2006-01-02T15:04:05")
+	return notesapi.NoteParams{
+		Title:     notesapi.String(note.Title),
+		Body:      notesapi.String(reminders + body),
+		Pinned:    notesapi.Bool(note.IsPinned),
+		Checklist: notesapi.Bool(checklist),
+		CreatedAt: usecToTime(note.CreatedTimestampUsec).UTC(),
+		UpdatedAt: usecToTime(note.UserEditedTimestampUsec).UTC(),
+	}
 }
 
-func fetchExistingNotes(c *Client) (map[string]bool, error) {
-	existing := make(map[string]bool)
+// importNote imports one Keep JSON file, logging its progress to lg.
+func (im *importer) importNote(source string, lg *log.Logger) (importResult, error) {
+	entry := im.journal.Get(source)
+	if entry != nil && entry.Done && !im.update {
+		lg.Printf("SKIP %s (journal: already imported as id=%d)", source, entry.NoteID)
+		return resultSkipped, nil
+	}
+	if entry != nil && !entry.Done && !im.resume && !im.update {
+		lg.Printf("SKIP %s (journal: partly imported as id=%d; rerun with --resume to finish it)", source, entry.NoteID)
+		return resultSkipped, nil
+	}
+
+	data, err := im.readFile(source)
+	if err != nil {
+		return 0, err
+	}
+	sum := checksum(data)
+	// With --update, a note is rewritten only if its Keep file changed since
+	// it was last imported. Entries from before checksums were recorded have
+	// none, so they count as changed; the checksum is recorded once the note
+	// has been rewritten.
+	changed := im.update && entry != nil && entry.Checksum != sum
+	if entry != nil && entry.Done && !changed {
+		lg.Printf("SKIP %s (journal: unchanged since imported as id=%d)", source, entry.NoteID)
+		return resultSkipped, nil
+	}
+
+	var note KeepNote
+	if err := json.Unmarshal(data, &note); err != nil {
+		return 0, fmt.Errorf("parse %s: %w", source, err)
+	}
+
+	params := im.noteParams(source, note)
+
+	if im.dryRun {
+		if changed {
+			return resultUpdated, im.printUpdatePlan(lg, source, entry.NoteID, note, params)
+		}
+		if entry != nil {
+			lg.Printf("DRY-RUN %s (would resume id=%d)", source, entry.NoteID)
+			return resultResumed, nil
+		}
+		return resultCreated, im.printPlan(lg, source, note, params)
+	}
 
-	for _, filter := range []string{"", "archived", "trash"} {
-		for page := 1; ; page++ {
-			url := fmt.Sprintf("%s/notes?limit=100&page=%d", apiBase, page)
-			if filter != "" {
-				url += "&filter=" + filter
+	c, journal := im.client, im.journal
+	result := resultCreated
+	if changed {
+		params.TagIDs, err = im.tagIDs(note)
+		if err != nil {
+			return 0, err
+		}
+		if params.TagIDs == nil {
+			// Labels removed in Keep are removed here too.
+			params.TagIDs = []int{}
+		}
+		params.CreatedAt = time.Time{}
+		if _, err := c.UpdateNote(entry.NoteID, params); err != nil {
+			return 0, err
+		}
+		if err := im.manifest.NoteUpdated(entry.NoteID, source); err != nil {
+			return 0, err
+		}
+		lg.Printf("UPDATED %s → id=%d title=%q", source, entry.NoteID, note.Title)
+		result = resultUpdated
+		entry.Checksum = sum
+		if err := journal.Record(source, entry); err != nil {
+			return 0, err
+		}
+	} else if entry != nil {
+		// A previous run created the note but did not finish every step.
+		lg.Printf("RESUME %s → id=%d", source, entry.NoteID)
+		result = resultResumed
+	} else {
+		key := im.existing.key(note.Title, *params.Body, params.CreatedAt)
+		id, claimed := im.existing.claim(key)
+		release := func() {
+			if claimed {
+				im.existing.release(key)
 			}
+		}
 
-			body, status, err := c.doJSON("GET", url, nil)
-			if err != nil {
-				return nil, fmt.Errorf("fetch notes (filter=%s, page=%d): %w", filter, page, err)
+		near := im.existing.nearDuplicates(note.Title, *params.Body)
+		if !claimed {
+			same := true
+			if id != 0 && im.existing.strategy == dedupContent {
+				// Same title and body; the attachments decide.
+				if same, err = im.existing.sameAttachments(c, id, note.Attachments); err != nil {
+					return 0, err
+				}
 			}
-			if status != http.StatusOK {
-				return nil, fmt.Errorf("fetch notes (%d): %s", status, body)
+			if same {
+				lg.Printf("SKIP %s (already exists)", source)
+				return resultSkipped, nil
 			}
+			near = append([]notesapi.Note{im.existing.notes[id]}, near...)
+		}
+		for _, n := range near {
+			lg.Printf("NEAR-DUPLICATE %s ~ id=%d title=%q", source, n.ID, n.Title)
+			im.nearDups.add(fmt.Sprintf("id=%d %q", n.ID, n.Title), source)
+		}
+		if len(near) > 0 && im.nearDupPolicy == nearDupSkip {
+			release()
+			lg.Printf("SKIP %s (near duplicate)", source)
+			return resultSkipped, nil
+		}
 
-			var resp NotesListResponse
-			if err := json.Unmarshal(body, &resp); err != nil {
-				return nil, fmt.Errorf("parse notes: %w", err)
+		params.TagIDs, err = im.tagIDs(note)
+		if err != nil {
+			release()
+			return 0, err
+		}
+
+		if len(near) > 0 && im.nearDupPolicy == nearDupUpdate {
+			entry, err = im.updateExisting(near[0].ID, note, params)
+			if err != nil {
+				release()
+				return 0, err
 			}
+			if err := im.manifest.NoteUpdated(entry.NoteID, source); err != nil {
+				return 0, err
+			}
+			lg.Printf("UPDATED %s → id=%d title=%q", source, entry.NoteID, note.Title)
+			result = resultUpdated
+		} else {
+			created, err := c.CreateNote(params)
+			if err != nil {
+				release()
+				return 0, err
+			}
+			if err := im.manifest.NoteCreated(created.ID, source); err != nil {
+				return 0, err
+			}
+			lg.Printf("CREATED %s → id=%d title=%q", source, created.ID, note.Title)
+			entry = &JournalEntry{NoteID: created.ID}
+		}
+		entry.Checksum = sum
+		if err := journal.Record(source, entry); err != nil {
+			return 0, err
+		}
+	}
+
+	noteID := entry.NoteID
+	complete := true
 
-			for _, n := range resp.Notes {
-				existing[dedupKey(n.Title, n.CreatedAt)] = true
+	// Archive if needed
+	if note.IsArchived && !entry.Archived {
+		if _, err := c.ArchiveNote(noteID); err != nil {
+			lg.Printf("  WARN archive %d: %v", noteID, err)
+			complete = false
+		} else {
+			lg.Printf("  ARCHIVED %d", noteID)
+			entry.Archived = true
+			if err := journal.Record(source, entry); err != nil {
+				return 0, err
 			}
+		}
+	}
 
-			if page >= resp.Pagination.Pages || len(resp.Notes) == 0 {
-				break
+	// Trash if needed
+	if note.IsTrashed && !entry.Trashed {
+		if err := c.DeleteNote(noteID); err != nil {
+			lg.Printf("  WARN trash %d: %v", noteID, err)
+			complete = false
+		} else {
+			lg.Printf("  TRASHED %d", noteID)
+			entry.Trashed = true
+			if err := journal.Record(source, entry); err != nil {
+				return 0, err
 			}
 		}
 	}
 
-	log.Printf("Found %d existing notes for dedup", len(existing))
-	return existing, nil
-}
+	// Share with collaborators
+	for _, email := range im.collaborators(note) {
+		if entry.Shares[email] {
+			continue
+		}
+		_, err := c.CreateShare(noteID, email)
+		switch {
+		case errors.Is(err, notesapi.ErrNotFound):
+			lg.Printf("  UNMATCHED collaborator %s (no Notes account)", email)
+			im.unmatched.add(email, source)
+		case errors.Is(err, notesapi.ErrValidation):
+			// Already shared, or the collaborator is this account.
+			lg.Printf("  SHARE %s not needed: %v", email, err)
+		case err != nil:
+			lg.Printf("  WARN share %s: %v", email, err)
+			complete = false
+			continue
+		default:
+			lg.Printf("  SHARED %d with %s", noteID, email)
+		}
+		if entry.Shares == nil {
+			entry.Shares = make(map[string]bool)
+		}
+		entry.Shares[email] = true
+		if err := journal.Record(source, entry); err != nil {
+			return 0, err
+		}
+	}
 
-// importResult indicates the outcome of importing a single note.
-type importResult int
+	// Upload attachments
+	for _, att := range note.Attachments {
+		if entry.Attachments[att.FilePath] {
+			continue
+		}
+		if err := im.uploadAttachment(noteID, att); errors.Is(err, errAttachmentTooLarge) {
+			// Retrying cannot help, so this does not hold back Done.
+			lg.Printf("  SKIP attachment %s: %v", att.FilePath, err)
+			continue
+		} else if err != nil {
+			lg.Printf("  WARN attachment %s: %v", att.FilePath, err)
+			complete = false
+			continue
+		}
+		lg.Printf("  ATTACHED %s to %d", att.FilePath, noteID)
+		if err := im.manifest.AttachmentUploaded(noteID, filepath.Base(att.FilePath)); err != nil {
+			return 0, err
+		}
+		if entry.Attachments == nil {
+			entry.Attachments = make(map[string]bool)
+		}
+		entry.Attachments[att.FilePath] = true
+		if err := journal.Record(source, entry); err != nil {
+			return 0, err
+		}
+	}
 
-const (
-	resultCreated importResult = iota
-	resultSkipped
-)
+	// Only a fully finished note is skipped on --resume; anything else is retried.
+	if complete {
+		entry.Done = true
+		if err := journal.Record(source, entry); err != nil {
+			return 0, err
+		}
+	}
 
-func importNote(c *Client, noteFile string, existing map[string]bool) (importResult, error) {
-	data, err := os.ReadFile(noteFile)
+	return result, nil
+}
+
+// updateExisting overwrites an existing note with a Keep note's content,
+// keeping its creation time, and returns a journal entry for it. The entry
+// starts from the note's current state, so the steps that follow neither
+// trash an already trashed note (which would delete it for good) nor upload
+// attachments it already has.
+func (im *importer) updateExisting(id int, note KeepNote, params notesapi.NoteParams) (*JournalEntry, error) {
+	params.CreatedAt = time.Time{}
+	updated, err := im.client.UpdateNote(id, params)
 	if err != nil {
-		return 0, fmt.Errorf("read %s: %w", noteFile, err)
+		return nil, err
 	}
+	entry := &JournalEntry{NoteID: id, Archived: updated.Archived, Trashed: updated.Trashed}
 
-	var note KeepNote
-	if err := json.Unmarshal(data, &note); err != nil {
-		return 0, fmt.Errorf("parse %s: %w", noteFile, err)
+	atts, err := im.client.ListAttachments(id)
+	if err != nil {
+		return nil, err
 	}
+	for _, att := range note.Attachments {
+		name := filepath.Base(att.FilePath)
+		if slices.ContainsFunc(atts, func(a notesapi.Attachment) bool { return a.Filename == name }) {
+			if entry.Attachments == nil {
+				entry.Attachments = make(map[string]bool)
+			}
+			entry.Attachments[att.FilePath] = true
+		}
+	}
+	return entry, nil
+}
 
-	createdAt := usecToTime(note.CreatedTimestampUsec).UTC().Format(time.RFC3339)
-	updatedAt := usecToTime(note.UserEditedTimestampUsec).UTC().Format(time.RFC3339)
+// collaborators returns the emails a note should be shared with: every Keep
+// sharee, including the original owner of a note that was shared with the
+// exporting user, except the importing account itself.
+func (im *importer) collaborators(note KeepNote) []string {
+	var emails []string
+	for _, s := range note.Sharees {
+		email := strings.TrimSpace(s.Email)
+		if email == "" || strings.EqualFold(email, im.self) || slices.Contains(emails, email) {
+			continue
+		}
+		emails = append(emails, email)
+	}
+	return emails
+}
 
-	key := dedupKey(note.Title, createdAt)
-	if existing[key] {
-		log.Printf("SKIP %s (already exists)", filepath.Base(noteFile))
-		return resultSkipped, nil
+// reportSet collects items for the final summary, such as collaborator
+// emails that have no Notes account, with the notes that mention them. It is
+// safe for concurrent use.
+type reportSet struct {
+	mu      sync.Mutex
+	sources map[string][]string
+}
+
+func (u *reportSet) add(email, source string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.sources == nil {
+		u.sources = make(map[string][]string)
 	}
+	u.sources[email] = append(u.sources[email], source)
+}
 
-	body := buildBody(note)
-	isChecklist := len(note.ListContent) > 0
+// tagIDs returns the IDs of a note's tags, creating missing ones, or nil if
+// it has none.
+func (im *importer) tagIDs(note KeepNote) ([]int, error) {
+	var ids []int
+	for _, t := range im.noteTags(note) {
+		id, err := im.tags.Ensure(t.name, t.color)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
 
-	payload := map[string]any{
-		"title":      note.Title,
-		"body":       body,
-		"pinned":     note.IsPinned,
-		"checklist":  isChecklist,
-		"created_at": createdAt,
-		"updated_at": updatedAt,
+// readFile reads a file from the Keep folder of the export.
+func (im *importer) readFile(name string) ([]byte, error) {
+	f, err := im.takeout.open(name)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
 	}
+	return data, nil
+}
 
-	respBody, status, err := c.doJSON("POST", apiBase+"/notes", payload)
+// uploadAttachment reads a Keep attachment from the Takeout export and
+// uploads it to the note.
+func (im *importer) uploadAttachment(noteID int, att KeepAttachment) error {
+	data, err := im.readAttachment(att.FilePath)
 	if err != nil {
-		return 0, fmt.Errorf("create note: %w", err)
+		return err
 	}
-	if status != http.StatusCreated {
-		return 0, fmt.Errorf("create note (%d): %s", status, respBody)
+	if int64(len(data)) > notesapi.MaxAttachmentBytes {
+		return fmt.Errorf("%w (%.1f MB)", errAttachmentTooLarge, float64(len(data))/(1<<20))
 	}
+	return im.client.UploadAttachments(noteID, []notesapi.File{{
+		Filename:    filepath.Base(att.FilePath),
+		ContentType: attachmentType(att),
+		Data:        data,
+	}})
+}
 
-	var created NoteResponse
-	if err := json.Unmarshal(respBody, &created); err != nil {
-		return 0, fmt.Errorf("parse created note: %w", err)
+// printUpdatePlan logs the request an --update run would send to rewrite a
+// previously imported note.
+func (im *importer) printUpdatePlan(lg *log.Logger, source string, noteID int, note KeepNote, params notesapi.NoteParams) error {
+	params.CreatedAt = time.Time{}
+	body, err := json.MarshalIndent(params, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", source, err)
+	}
+	lg.Printf("DRY-RUN %s (changed)\n  PATCH /api/v1/notes/%d\n  %s", source, noteID, body)
+	tags := im.noteTags(note)
+	if len(tags) == 0 {
+		lg.Printf("  tag_ids: [] (removes every tag)")
+		return nil
 	}
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	lg.Printf("  tag_ids: tags %s (created if missing)", strings.Join(names, ", "))
+	return nil
+}
 
-	noteURL := fmt.Sprintf("%s/notes/%d", apiBase, created.ID)
-	log.Printf("CREATED %s → id=%d title=%q", filepath.Base(noteFile), created.ID, note.Title)
+var errAttachmentTooLarge = errors.New("larger than the 25 MB attachment limit")
 
-	// Archive if needed
-	if note.IsArchived {
-		if _, status, err := c.doJSON("PATCH", noteURL+"/archive", nil); err != nil {
-			log.Printf("  WARN archive %d: %v", created.ID, err)
-		} else if status != http.StatusOK {
-			log.Printf("  WARN archive %d: status %d", created.ID, status)
-		} else {
-			log.Printf("  ARCHIVED %d", created.ID)
+// printPlan logs the requests importNote would send for a note, with the
+// exact JSON body of the create request. The new note's ID is shown as :id.
+func (im *importer) printPlan(lg *log.Logger, source string, note KeepNote, params notesapi.NoteParams) error {
+	body, err := json.MarshalIndent(params, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", source, err)
+	}
+	lg.Printf("DRY-RUN %s\n  POST /api/v1/notes\n  %s", source, body)
+	if tags := im.noteTags(note); len(tags) > 0 {
+		names := make([]string, len(tags))
+		for i, t := range tags {
+			names[i] = t.name
 		}
+		lg.Printf("  tag_ids: tags %s (created if missing)", strings.Join(names, ", "))
+	}
+	if note.IsArchived {
+		lg.Printf("  PATCH /api/v1/notes/:id/archive")
 	}
-
-	// Trash if needed
 	if note.IsTrashed {
-		if _, status, err := c.doJSON("DELETE", noteURL, nil); err != nil {
-			log.Printf("  WARN trash %d: %v", created.ID, err)
-		} else if status != http.StatusOK {
-			log.Printf("  WARN trash %d: status %d", created.ID, status)
-		} else {
-			log.Printf("  TRASHED %d", created.ID)
-		}
+		lg.Printf("  DELETE /api/v1/notes/:id")
+	}
+	for _, email := range im.collaborators(note) {
+		lg.Printf("  POST /api/v1/notes/:id/shares {\"email\":%q}", email)
 	}
-
-	// Upload attachments
 	for _, att := range note.Attachments {
-		attPath := filepath.Join(keepDir, att.FilePath)
-		if err := c.uploadFile(noteURL+"/attachments", attPath); err != nil {
-			log.Printf("  WARN attachment %s: %v", att.FilePath, err)
-		} else {
-			log.Printf("  ATTACHED %s to %d", att.FilePath, created.ID)
-		}
+		lg.Printf("  POST /api/v1/notes/:id/attachments files[]=%s (%s)", filepath.Base(att.FilePath), attachmentType(att))
 	}
-
-	// Record in dedup map so re-runs within same execution skip too
-	existing[key] = true
-
-	return resultCreated, nil
+	return nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "list-imported" {
+		listImported(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rollback" {
+		rollback(os.Args[2:])
+		return
+	}
+
+	configPath := flag.String("config", "", "JSON config file (default gkeep.json, if present)")
+	registerConfigFlags(flag.CommandLine)
+	resume := flag.Bool("resume", false, "Resume an interrupted run from the journal, finishing half-imported notes")
+	update := flag.Bool("update", false, "Rewrite notes whose Keep file changed since the journal recorded them (implies reading the journal)")
+	dryRun := flag.Bool("dry-run", false, "Print the requests each note would send, without contacting Notes")
+	token := flag.String("token", "", "Pre-issued Notes API token (overrides all other credential sources)")
+	manifestPath := flag.String("manifest", manifest.DefaultPath("gkeep"), "File recording what this run creates, for gkeep-import rollback")
+	flag.Parse()
+
 	log.SetFlags(log.Ltime)
 
-	email, password, err := parseCredentials(credentialsFile)
+	cfg, err := resolveConfig(flag.CommandLine, *configPath)
 	if err != nil {
-		log.Fatalf("Credentials: %v", err)
+		log.Fatalf("Config: %v", err)
 	}
-
-	client := &Client{http: &http.Client{Timeout: 30 * time.Second}}
-
-	if err := authenticate(client, email, password); err != nil {
-		log.Fatalf("Auth: %v", err)
+	if cfg.NotesURL == "" && !*dryRun {
+		log.Fatalf("Config: --notes-url (or NOTES_URL, or notes_url in the config file) is required")
 	}
+	limit, window, _ := parseRate(cfg.Rate)
 
-	existing, err := fetchExistingNotes(client)
+	journal, err := openJournal(cfg.Journal)
 	if err != nil {
-		log.Fatalf("Fetch existing: %v", err)
+		log.Fatalf("Journal: %v", err)
+	}
+	if *resume {
+		log.Printf("Resuming from %s (%d notes recorded)", cfg.Journal, journal.Len())
+	} else if *update {
+		log.Printf("Updating notes recorded in %s (%d notes)", cfg.Journal, journal.Len())
+	} else if journal.Len() > 0 {
+		log.Printf("Skipping the %d notes recorded in %s", journal.Len(), cfg.Journal)
 	}
 
-	files, err := filepath.Glob(filepath.Join(keepDir, "*.json"))
+	tk, err := openTakeout(cfg.Takeout)
 	if err != nil {
-		log.Fatalf("Glob: %v", err)
+		log.Fatalf("Takeout: %v", err)
+	}
+	defer tk.Close()
+
+	im := &importer{
+		takeout:       tk,
+		journal:       journal,
+		nearDupPolicy: cfg.NearDuplicates,
+		provenance:    cfg.Provenance,
+		startedAt:     time.Now().UTC().Truncate(time.Second),
+		importerName:  provenance.Importer("gkeep-import"),
+		update:        *update,
+		resume:        *resume,
+		dryRun:        *dryRun,
+	}
+	if cfg.ColorTags {
+		im.colorTags = colorTagNames(cfg.ColorTable)
 	}
-	log.Printf("Found %d JSON files to import", len(files))
 
-	var nCreated, nSkipped, nErrored int
-	for _, f := range files {
-		result, err := importNote(client, f, existing)
+	if !*dryRun {
+		creds, err := loadCredentials(*token, cfg)
 		if err != nil {
-			log.Printf("ERROR %s: %v", filepath.Base(f), err)
+			log.Fatalf("Credentials: %v", err)
+		}
+
+		im.client = notesapi.NewClient(cfg.NotesURL, "")
+		im.client.Limiter = notesapi.NewTokenBucket(limit, window)
+		im.client.Logf = log.Printf
+
+		if err := authenticate(im.client, creds); err != nil {
+			log.Fatalf("Auth: %v", err)
+		}
+		im.self = creds.Email
+
+		m, err := manifest.Create(*manifestPath, "gkeep", cfg.NotesURL)
+		if err != nil {
+			log.Fatalf("Manifest: %v", err)
+		}
+		im.manifest = m.Account("", creds.Email)
+		log.Printf("Recording created notes in %s", m.Path())
+
+		im.existing, err = fetchExistingNotes(im.client, cfg.Dedup)
+		if err != nil {
+			log.Fatalf("Fetch existing: %v", err)
+		}
+		im.tags, err = im.client.FetchTags()
+		if err != nil {
+			log.Fatalf("Fetch tags: %v", err)
+		}
+		im.tags.OnCreate = func(tag notesapi.Tag) error {
+			return im.manifest.TagCreated(tag.ID, tag.Name)
+		}
+	}
+
+	files := tk.notes()
+	log.Printf("Found %d JSON files to import in %s", len(files), tk)
+
+	type outcome struct {
+		result importResult
+		err    error
+		log    bytes.Buffer
+	}
+
+	var nCreated, nUpdated, nResumed, nSkipped, nErrored int
+	notesapi.RunOrdered(len(files), cfg.Workers, func(i int) *outcome {
+		o := &outcome{}
+		lg := log.New(&o.log, "", log.Flags())
+		o.result, o.err = im.importNote(files[i], lg)
+		return o
+	}, func(i int, o *outcome) {
+		log.Writer().Write(o.log.Bytes())
+		if o.err != nil {
+			log.Printf("ERROR %s: %v", files[i], o.err)
 			nErrored++
-		} else if result == resultSkipped {
+		} else if o.result == resultSkipped {
 			nSkipped++
+		} else if o.result == resultResumed {
+			nResumed++
+		} else if o.result == resultUpdated {
+			nUpdated++
 		} else {
 			nCreated++
 		}
+	})
+
+	if *dryRun {
+		log.Printf("Dry run: %d would be created, %d updated, %d resumed, %d skipped, %d errors (of %d total)", nCreated, nUpdated, nResumed, nSkipped, nErrored, len(files))
+		return
 	}
+	log.Printf("Done: %d created, %d updated, %d resumed, %d skipped, %d errors (of %d total); %d tags created", nCreated, nUpdated, nResumed, nSkipped, nErrored, len(files), im.tags.Created())
+	log.Printf("Manifest: %s (undo with: gkeep-import rollback %s)", *manifestPath, *manifestPath)
 
-	log.Printf("Done: %d created, %d skipped, %d errors (of %d total)", nCreated, nSkipped, nErrored, len(files))
+	if n := len(im.unmatched.sources); n > 0 {
+		log.Printf("%d Keep collaborator(s) have no Notes account; these notes were not shared with them:", n)
+		for _, email := range slices.Sorted(maps.Keys(im.unmatched.sources)) {
+			log.Printf("  %s: %s", email, strings.Join(im.unmatched.sources[email], ", "))
+		}
+	}
+	if n := len(im.nearDups.sources); n > 0 {
+		log.Printf("%d existing note(s) nearly duplicate imported notes (near duplicates: %s):", n, im.nearDupPolicy)
+		for _, note := range slices.Sorted(maps.Keys(im.nearDups.sources)) {
+			log.Printf("  %s: %s", note, strings.Join(im.nearDups.sources[note], ", "))
+		}
+	}
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reminderTag is added to every note that had a Keep reminder.
+const reminderTag = "reminder"
+
+type KeepReminder struct {
+	DueDate KeepDate `json:"dueDate"`
+	// Recurrence is an iCalendar RRULE such as "FREQ=WEEKLY;BYDAY=MO", or
+	// empty for a one-off reminder.
+	Recurrence  string `json:"recurrence"`
+	Description string `json:"description"`
+}
+
+// KeepDate is a reminder's due date in the user's time zone at the time,
+// which the export does not record. Time is nil for all-day reminders.
+type KeepDate struct {
+	Year  int       `json:"year"`
+	Month int       `json:"month"`
+	Day   int       `json:"day"`
+	Time  *KeepTime `json:"time"`
+}
+
+type KeepTime struct {
+	Hour   int `json:"hour"`
+	Minute int `json:"minute"`
+	Second int `json:"second"`
+}
+
+// String formats the date as ISO 8601 without a zone, e.g.
+// "2024-03-01T09:00:00", or "2024-03-01" for an all-day reminder.
+func (d KeepDate) String() string {
+	s := fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	if d.Time != nil {
+		s += fmt.Sprintf("T%02d:%02d:%02d", d.Time.Hour, d.Time.Minute, d.Time.Second)
+	}
+	return s
+}
+
+// reminderFrontMatter renders a note's reminders as a front-matter block to
+// put at the top of its body, or after the list of a checklist, or "" if it
+// has none:
+//
+//	---
+//	reminders:
+//	  - due: 2024-03-01T09:00:00
+//	    recurrence: "FREQ=WEEKLY;BYDAY=MO"
+//	---
+//
+// The recurrence and description are always double-quoted, so scripts can
+// recover the reminders with any YAML parser whatever the text.
+func reminderFrontMatter(reminders []KeepReminder) string {
+	var lines []string
+	for _, r := range reminders {
+		if r.DueDate.Year == 0 {
+			continue
+		}
+		lines = append(lines, "  - due: "+r.DueDate.String())
+		if r.Recurrence != "" {
+			lines = append(lines, "    recurrence: "+yamlQuote(r.Recurrence))
+		}
+		if r.Description != "" {
+			lines = append(lines, "    description: "+yamlQuote(r.Description))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "---\nreminders:\n" + strings.Join(lines, "\n") + "\n---\n\n"
+}
+
+// hasReminder reports whether a note has a reminder that is carried over.
+func hasReminder(note KeepNote) bool {
+	return reminderFrontMatter(note.Reminders) != ""
+}
+
+// yamlQuote returns s as a YAML double-quoted scalar. Backslashes, quotes
+// and characters YAML does not allow printed are escaped.
+func yamlQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			switch {
+			case r < 0x20 || r >= 0x7f && r <= 0x9f:
+				fmt.Fprintf(&sb, `\x%02X`, r)
+			case r == 0x2028 || r == 0x2029 || r == 0xfeff || r == 0xfffe || r == 0xffff:
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			default:
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
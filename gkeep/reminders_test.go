@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbright/notesapi/provenance"
+)
+
+func TestYAMLQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"FREQ=WEEKLY;BYDAY=MO", `"FREQ=WEEKLY;BYDAY=MO"`},
+		{"- yes", `"- yes"`},
+		{"no", `"no"`},
+		{"Call: mum # weekly", `"Call: mum # weekly"`},
+		{`say "hi" \ bye`, `"say \"hi\" \\ bye"`},
+		{"two\nlines\ttab", `"two\nlines\ttab"`},
+		{"bell\a del\x7f", `"bell\x07 del\x7F"`},
+		{"next\u0085line sep\u2028", `"next\x85line sep\u2028"`},
+		{"café ☕", `"café ☕"`},
+		{" padded ", `" padded "`},
+	}
+	for _, tt := range tests {
+		if got := yamlQuote(tt.in); got != tt.want {
+			t.Errorf("yamlQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReminderFrontMatter(t *testing.T) {
+	got := reminderFrontMatter([]KeepReminder{
+		{DueDate: KeepDate{Year: 2024, Month: 3, Day: 1, Time: &KeepTime{Hour: 9}}, Recurrence: "FREQ=WEEKLY;BYDAY=MO", Description: "- yes"},
+		{DueDate: KeepDate{Year: 2024, Month: 4, Day: 2}},
+		{Description: "no due date"},
+	})
+	want := "---\nreminders:\n" +
+		"  - due: 2024-03-01T09:00:00\n" +
+		"    recurrence: \"FREQ=WEEKLY;BYDAY=MO\"\n" +
+		"    description: \"- yes\"\n" +
+		"  - due: 2024-04-02\n" +
+		"---\n\n"
+	if got != want {
+		t.Errorf("reminderFrontMatter =\n%s\nwant:\n%s", got, want)
+	}
+	if got := reminderFrontMatter([]KeepReminder{{Description: "no due date"}}); got != "" {
+		t.Errorf("reminderFrontMatter with no due date = %q, want \"\"", got)
+	}
+}
+
+func TestChecklistBlocksFollowList(t *testing.T) {
+	im := &importer{provenance: provenance.FrontMatter, startedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+	note := KeepNote{
+		ListContent: []KeepListItem{{Text: "milk"}},
+		Reminders:   []KeepReminder{{DueDate: KeepDate{Year: 2024, Month: 4, Day: 2}}},
+	}
+	body := *im.noteParams("list.json", note).Body
+	want := "- [ ] milk\n\n---\nreminders:\n  - due: 2024-04-02\n---\n\n<!-- provenance\n"
+	if !strings.HasPrefix(body, want) {
+		t.Errorf("checklist body = %q, want it to start %q", body, want)
+	}
+	if p, ok := provenance.Parse(body); !ok || p.SourceID != "list.json" {
+		t.Errorf("Parse = %+v, %v; want the provenance of list.json", p, ok)
+	}
+
+	note.ListContent = nil
+	note.TextContent = "milk"
+	body = *im.noteParams("text.json", note).Body
+	if !strings.HasPrefix(body, "---\nreminders:\n") {
+		t.Errorf("text note body = %q, want the reminders first", body)
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mbright/notesapi"
+)
+
+// htmlToMarkdown converts Keep's textContentHtml to the Markdown dialect the
+// Notes body uses (rendered by Redcarpet with hard line breaks):
+//
+//   - bold, italic and strikethrough become **, _ and ~~, or <strong>, <em>
+//     and <del> where they begin or end inside a word
+//   - underline, which Markdown lacks, becomes inline <u>, which Notes renders
+//   - h1–h6 become # headings, and links become [text](url)
+//   - paragraphs and <br> become single line breaks, as in textContent
+//   - characters of the text that Markdown would read as formatting are
+//     escaped with a backslash, except in bare URLs
+//
+// Bold, italic and so on may be given by tags or by a span's inline style,
+// as Keep writes them. Unknown elements are transparent. An error is
+// returned for HTML that cannot be tokenized or that holds no text, so the
+// caller can fall back to the plain textContent.
+func htmlToMarkdown(src string) (string, error) {
+	d := xml.NewDecoder(strings.NewReader("<root>" + src + "</root>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var c htmlConverter
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parse html: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			c.start(strings.ToLower(t.Name.Local), t.Attr)
+		case xml.EndElement:
+			c.end(strings.ToLower(t.Name.Local))
+		case xml.CharData:
+			c.text(string(t))
+		}
+	}
+
+	md := c.render()
+	if strings.TrimSpace(md) == "" {
+		return "", errors.New("html has no text")
+	}
+	return md, nil
+}
+
+type blockKind int
+
+const (
+	blockPara blockKind = iota
+	blockHeading
+	blockItem
+)
+
+type mdStyle struct {
+	bold, italic, underline, strike bool
+	href                            string
+}
+
+type mdRun struct {
+	mdStyle
+	text string // "\n" for a line break
+}
+
+type mdBlock struct {
+	kind   blockKind
+	prefix string
+	runs   []mdRun
+}
+
+type htmlList struct {
+	ordered bool
+	n       int
+}
+
+// htmlConverter accumulates blocks of styled text while walking the tokens.
+type htmlConverter struct {
+	blocks []mdBlock
+	cur    *mdBlock
+	elems  []string
+	styles []mdStyle
+	lists  []htmlList
+	skip   int // depth inside <script> or <style>
+}
+
+func (c *htmlConverter) style() mdStyle {
+	if len(c.styles) == 0 {
+		return mdStyle{}
+	}
+	return c.styles[len(c.styles)-1]
+}
+
+func (c *htmlConverter) startBlock(kind blockKind, prefix string) {
+	c.blocks = append(c.blocks, mdBlock{kind: kind, prefix: prefix})
+	c.cur = &c.blocks[len(c.blocks)-1]
+}
+
+func (c *htmlConverter) start(name string, attrs []xml.Attr) {
+	s := c.style()
+	switch name {
+	case "b", "strong":
+		s.bold = true
+	case "i", "em":
+		s.italic = true
+	case "u", "ins":
+		s.underline = true
+	case "s", "strike", "del":
+		s.strike = true
+	case "a":
+		s.href = attr(attrs, "href")
+	case "script", "style":
+		c.skip++
+	case "br":
+		if c.cur == nil {
+			c.startBlock(blockPara, "")
+		}
+		c.cur.runs = append(c.cur.runs, mdRun{text: "\n"})
+	case "p", "div":
+		c.startBlock(blockPara, "")
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.startBlock(blockHeading, strings.Repeat("#", int(name[1]-'0'))+" ")
+	case "ul", "ol":
+		c.cur = nil
+		c.lists = append(c.lists, htmlList{ordered: name == "ol"})
+	case "li":
+		marker, depth := "- ", 0
+		if n := len(c.lists); n > 0 {
+			depth = n - 1
+			l := &c.lists[n-1]
+			if l.ordered {
+				l.n++
+				marker = fmt.Sprintf("%d. ", l.n)
+			}
+		}
+		c.startBlock(blockItem, strings.Repeat("  ", depth)+marker)
+	}
+	applyInlineStyle(&s, attr(attrs, "style"))
+	c.elems = append(c.elems, name)
+	c.styles = append(c.styles, s)
+}
+
+func (c *htmlConverter) end(name string) {
+	// Pop back to the matching element; stray end tags are ignored.
+	for i := len(c.elems) - 1; i >= 0; i-- {
+		if c.elems[i] != name {
+			continue
+		}
+		c.elems, c.styles = c.elems[:i], c.styles[:i]
+		switch name {
+		case "script", "style":
+			c.skip--
+		case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "li":
+			c.cur = nil
+		case "ul", "ol":
+			c.cur = nil
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+		return
+	}
+}
+
+func (c *htmlConverter) text(s string) {
+	if c.skip > 0 {
+		return
+	}
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	// Whitespace between tags that only lays out the source.
+	if strings.TrimSpace(s) == "" && strings.Contains(s, "\n") {
+		return
+	}
+	if c.cur == nil {
+		c.startBlock(blockPara, "")
+	}
+	c.cur.runs = append(c.cur.runs, mdRun{mdStyle: c.style(), text: s})
+}
+
+// applyInlineStyle reads the CSS properties Keep uses for emphasis.
+func applyInlineStyle(s *mdStyle, css string) {
+	for _, decl := range strings.Split(css, ";") {
+		prop, val, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		val = strings.ToLower(strings.TrimSpace(val))
+		switch prop {
+		case "font-weight":
+			if n, err := strconv.Atoi(val); val == "bold" || val == "bolder" || err == nil && n >= 600 {
+				s.bold = true
+			}
+		case "font-style":
+			if val == "italic" || val == "oblique" {
+				s.italic = true
+			}
+		case "text-decoration", "text-decoration-line":
+			if strings.Contains(val, "underline") {
+				s.underline = true
+			}
+			if strings.Contains(val, "line-through") {
+				s.strike = true
+			}
+		}
+	}
+}
+
+func attr(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// render joins the blocks into Markdown. Headings and lists are set off by
+// blank lines so they are recognised; other blocks are one line apart.
+func (c *htmlConverter) render() string {
+	var lines []string
+	prev := blockPara
+	for i, b := range c.blocks {
+		text := b.prefix + renderRuns(b.runs)
+		if b.kind == blockPara {
+			// A paragraph holding just <br> is Keep's empty line.
+			text = strings.TrimSuffix(text, "\n")
+		}
+		apart := b.kind == blockHeading || prev == blockHeading || (b.kind == blockItem) != (prev == blockItem)
+		if i > 0 && apart && len(lines) > 0 && lines[len(lines)-1] != "" && text != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, text)
+		prev = b.kind
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// A span is one style, or a link, held over consecutive runs of a line. The
+// characters of text just before and after it decide how it is marked up.
+type mdSpan struct {
+	kind          spanKind
+	href          string
+	text          string
+	before, after rune // 0 at the edge of the line
+}
+
+type spanKind int
+
+// The order in which spans that begin together are opened.
+const (
+	spanLink spanKind = iota
+	spanStrike
+	spanBold
+	spanItalic
+	spanUnderline
+)
+
+// spans lists the spans a style is made of, in opening order.
+func (s mdStyle) spans() []mdSpan {
+	var out []mdSpan
+	if s.href != "" {
+		out = append(out, mdSpan{kind: spanLink, href: s.href})
+	}
+	for _, k := range []struct {
+		on   bool
+		kind spanKind
+	}{{s.strike, spanStrike}, {s.bold, spanBold}, {s.italic, spanItalic}, {s.underline, spanUnderline}} {
+		if k.on {
+			out = append(out, mdSpan{kind: k.kind})
+		}
+	}
+	return out
+}
+
+func (sp mdSpan) same(o mdSpan) bool { return sp.kind == o.kind && sp.href == o.href }
+
+func (sp mdSpan) sameAs(o *mdSpan) bool { return sp.same(*o) }
+
+// markers returns the text that opens and closes a span. Redcarpet, with
+// no_intra_emphasis, only sees emphasis that starts after a space, "(" or
+// ">" and ends before a non-word character, so emphasis inside a word is
+// written as inline HTML instead.
+func (sp *mdSpan) markers() (string, string) {
+	inWord := !(sp.before == 0 || unicode.IsSpace(sp.before) || sp.before == '(' || sp.before == '>') ||
+		unicode.IsLetter(sp.after) || unicode.IsDigit(sp.after)
+	switch sp.kind {
+	case spanLink:
+		if sp.text == sp.href {
+			return "", "" // autolinked
+		}
+		return "[", "](" + notesapi.EscapeLinkTarget(sp.href) + ")"
+	case spanStrike:
+		if !inWord {
+			return "~~", "~~"
+		}
+		return "<del>", "</del>"
+	case spanBold:
+		if !inWord {
+			return "**", "**"
+		}
+		return "<strong>", "</strong>"
+	case spanItalic:
+		if !inWord {
+			return "_", "_"
+		}
+		return "<em>", "</em>"
+	}
+	return "<u>", "</u>"
+}
+
+// mdEvent is a piece of rendered output: text, or a span opening or closing.
+type mdEvent struct {
+	text  string
+	open  *mdSpan
+	close *mdSpan
+}
+
+// textEscaper escapes the characters that would start Markdown formatting,
+// a link or inline HTML within a line.
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "~", `\~`,
+)
+
+// bareURL matches a URL that Redcarpet links by itself, which is left as it
+// is so that it stays one link.
+var bareURL = regexp.MustCompile(`(?:https?://|ftp://|www\.|mailto:)\S+`)
+
+// escapeText escapes the text of a run so that it reads as itself.
+func escapeText(s string) string {
+	var sb strings.Builder
+	prev := 0
+	for _, m := range bareURL.FindAllStringIndex(s, -1) {
+		sb.WriteString(textEscaper.Replace(s[prev:m[0]]))
+		sb.WriteString(s[m[0]:m[1]])
+		prev = m[1]
+	}
+	sb.WriteString(textEscaper.Replace(s[prev:]))
+	return sb.String()
+}
+
+// blockStart matches the start of a line that Markdown would read as a
+// heading, quote, list item, rule or heading underline.
+var blockStart = regexp.MustCompile(`^( *)(?:([#>+=-])|(\d+)\.( |$))`)
+
+// escapeLineStart escapes the start of a line of text that would otherwise
+// begin a block.
+func escapeLineStart(s string) string {
+	m := blockStart.FindStringSubmatchIndex(s)
+	switch {
+	case m == nil:
+		return s
+	case m[4] >= 0: // a marker character
+		return s[:m[4]] + `\` + s[m[4]:]
+	}
+	// A number followed by a period.
+	return s[:m[7]] + `\` + s[m[7]:]
+}
+
+// renderRuns wraps runs of styled text in Markdown markers. Between two runs
+// only the spans that end are closed and only those that begin are opened
+// (spans opened after one that ends are closed and opened again, as the
+// markers must nest). Spaces around a run are kept outside the markers that
+// start or end there, so the emphasis is recognised.
+func renderRuns(runs []mdRun) string {
+	var events []mdEvent
+	var stack, closed []*mdSpan
+	var last rune     // the last character of text on the line, 0 at its start
+	pending := ""     // spaces whose place among the markers is not yet known
+	lineStart := true // whether nothing but spaces is written on the line yet
+	emit := func(text string) {
+		if text == "" {
+			return
+		}
+		first, _ := utf8.DecodeRuneInString(text)
+		for _, sp := range closed {
+			sp.after = first
+		}
+		closed = closed[:0]
+		for _, sp := range stack {
+			sp.text += text
+		}
+		out := escapeText(text)
+		if lineStart {
+			out = escapeLineStart(out)
+			lineStart = strings.TrimSpace(text) == ""
+		}
+		events = append(events, mdEvent{text: out})
+		last, _ = utf8.DecodeLastRuneInString(text)
+	}
+	closeFrom := func(k int) {
+		for i := len(stack) - 1; i >= k; i-- {
+			events = append(events, mdEvent{close: stack[i]})
+			closed = append(closed, stack[i])
+		}
+		stack = stack[:k]
+	}
+	endLine := func() {
+		closeFrom(0)
+		emit(pending)
+		pending = ""
+		closed, last = closed[:0], 0
+		lineStart = true
+	}
+
+	for _, r := range runs {
+		if r.text == "\n" {
+			endLine()
+			events = append(events, mdEvent{text: "\n"})
+			continue
+		}
+		core := strings.TrimSpace(r.text)
+		if core == "" {
+			pending += r.text
+			continue
+		}
+		lead := r.text[:strings.Index(r.text, core)]
+		trail := r.text[len(lead)+len(core):]
+
+		want := r.spans()
+		k := 0
+		for k < len(stack) && slices.ContainsFunc(want, stack[k].same) {
+			k++
+		}
+		closeFrom(k)
+		emit(pending + lead)
+		pending = trail
+		// A marker just after another one that closed is inside a word.
+		before := last
+		if len(closed) > 0 {
+			before = 'x'
+		}
+		for _, w := range want {
+			if slices.ContainsFunc(stack, w.sameAs) {
+				continue
+			}
+			sp := &mdSpan{kind: w.kind, href: w.href, before: before}
+			stack = append(stack, sp)
+			events = append(events, mdEvent{open: sp})
+			lineStart = false
+		}
+		emit(core)
+	}
+	endLine()
+
+	var sb strings.Builder
+	for _, e := range events {
+		switch {
+		case e.open != nil:
+			open, _ := e.open.markers()
+			sb.WriteString(open)
+		case e.close != nil:
+			_, close := e.close.markers()
+			sb.WriteString(close)
+		default:
+			sb.WriteString(e.text)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"plain", `<p>Hello</p>`, "Hello"},
+		{"bold", `<p><b>bold</b> text</p>`, "**bold** text"},
+		{"bold then punctuation", `<p><b>bold</b>.</p>`, "**bold**."},
+		{"italic", `<p><i>italic</i></p>`, "_italic_"},
+		{"strike", `<p><s>gone</s></p>`, "~~gone~~"},
+		{"underline", `<p><u>under</u></p>`, "<u>under</u>"},
+		{"inline style", `<p><span style="font-weight: 700; font-style: italic">both</span></p>`, "**_both_**"},
+		{"spaces outside markers", `<p>a<b> bold </b>b</p>`, "a **bold** b"},
+
+		{"nested, style begins", `<p><b>bold <i>both</i></b></p>`, "**bold _both_**"},
+		{"nested, style ends", `<p><b><i>both</i> bold</b></p>`, "**_both_ bold**"},
+		{"nested, no space", `<p><b>bold<i>both</i></b></p>`, "**bold<em>both</em>**"},
+		{"overlapping", `<p><i>a <b>b</b></i><b> c</b></p>`, "_a **b**_ **c**"},
+		{"inside a word", `<p>un<b>believ</b>able</p>`, "un<strong>believ</strong>able"},
+		{"after a parenthesis", `<p>(<i>aside</i>)</p>`, "(_aside_)"},
+
+		{"link", `<p><a href="https://example.com">site</a></p>`, "[site](https://example.com)"},
+		{"autolink", `<p><a href="https://example.com">https://example.com</a></p>`, "https://example.com"},
+		{"bold link", `<p><b>see <a href="https://example.com">here</a> now</b></p>`, "**see [here](https://example.com) now**"},
+
+		{"br", `<p>one<br>two</p>`, "one\ntwo"},
+		{"br in bold", `<p><b>one<br>two</b></p>`, "**one**\n**two**"},
+		{"empty line", `<p>one</p><p><br></p><p>two</p>`, "one\n\ntwo"},
+
+		{"heading", `<h1>Title</h1><p>text</p>`, "# Title\n\ntext"},
+		{"subheading", `<p>a</p><h3>Part</h3><p>b</p>`, "a\n\n### Part\n\nb"},
+		{"list", `<p>Items:</p><ul><li>one</li><li>two</li></ul><p>after</p>`, "Items:\n\n- one\n- two\n\nafter"},
+		{"ordered list", `<ol><li>first</li><li>second</li></ol>`, "1. first\n2. second"},
+		{"nested list", `<ul><li>a<ul><li>b</li></ul></li><li>c</li></ul>`, "- a\n  - b\n- c"},
+
+		{"literal emphasis", `<p>2*3*4 and snake_case_name</p>`, `2\*3\*4 and snake\_case\_name`},
+		{"literal brackets", `<p>[x] done, see [1]</p>`, `\[x\] done, see \[1\]`},
+		{"literal code and html", "<p>`rm` &lt;b&gt; a\\b ~~no~~</p>", "\\`rm\\` \\<b> a\\\\b \\~\\~no\\~\\~"},
+		{"literal heading", `<p># not a heading</p>`, `\# not a heading`},
+		{"literal quote", `<p>&gt; not a quote</p>`, `\> not a quote`},
+		{"literal list items", `<p>- not an item<br>+ nor this<br>1. nor this<br>2024. a year</p>`, "\\- not an item\n\\+ nor this\n1\\. nor this\n2024\\. a year"},
+		{"literal underline", `<p>Title<br>===</p>`, "Title\n\\==="},
+		{"line start after spaces", `<p>  # still escaped</p>`, `  \# still escaped`},
+		{"only at line start", `<p>a # b - c 1. d</p>`, `a # b - c 1. d`},
+		{"bold at line start", `<p><b># tag</b></p>`, `**# tag**`},
+		{"literal in heading", `<h2>#1 *best*</h2>`, `## \#1 \*best\*`},
+		{"bare URL", `<p>see https://example.com/a_b*c for more_info</p>`, `see https://example.com/a_b*c for more\_info`},
+		{"link text escaped", `<p><a href="https://example.com">[docs]</a></p>`, `[\[docs\]](https://example.com)`},
+		{"link target escaped", `<p><a href="https://example.com/my file (1).pdf">file</a></p>`, `[file](https://example.com/my%20file%20%281%29.pdf)`},
+
+		{"script", `<p>x</p><script>alert(1)</script>`, "x"},
+		{"entity", `<p>a&nbsp;&amp;&nbsp;b</p>`, "a & b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := htmlToMarkdown(tt.html)
+			if err != nil {
+				t.Fatalf("htmlToMarkdown(%q): %v", tt.html, err)
+			}
+			if got != tt.want {
+				t.Errorf("htmlToMarkdown(%q) = %q, want %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLToMarkdownNoText(t *testing.T) {
+	for _, html := range []string{``, `<p></p>`, `<p><br></p>`, `<script>x</script>`} {
+		if got, err := htmlToMarkdown(html); err == nil {
+			t.Errorf("htmlToMarkdown(%q) = %q, want an error", html, got)
+		}
+	}
+}
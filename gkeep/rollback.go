@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/credentials"
+	"github.com/mbright/notesapi/manifest"
+)
+
+// rollback implements the rollback command, which undoes an import run
+// recorded in a manifest: notes it created are deleted for good, tags it
+// created are deleted if unused, and attachments it added to existing notes
+// are removed.
+func rollback(args []string) {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gkeep-import rollback [flags] <manifest>\n")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "", "JSON config file (default gkeep.json, if present)")
+	registerConfigFlags(fs)
+	token := fs.String("token", "", "Pre-issued Notes API token (overrides all other credential sources)")
+	yes := fs.Bool("yes", false, "Delete without asking for confirmation")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	log.SetFlags(log.Ltime)
+
+	m, err := manifest.Load(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Manifest: %v", err)
+	}
+	cfg, err := resolveConfig(fs, *configPath)
+	if err != nil {
+		log.Fatalf("Config: %v", err)
+	}
+	switch {
+	case cfg.NotesURL == "":
+		cfg.NotesURL = m.NotesURL
+	case m.NotesURL != "" && strings.TrimRight(cfg.NotesURL, "/") != strings.TrimRight(m.NotesURL, "/"):
+		log.Fatalf("Config: the manifest is for %s, not %s", m.NotesURL, cfg.NotesURL)
+	}
+	if cfg.NotesURL == "" {
+		log.Fatalf("Config: --notes-url (or NOTES_URL, or notes_url in the config file) is required")
+	}
+
+	var pending []*manifest.Account
+	for _, a := range m.Accounts {
+		if !a.Empty() {
+			pending = append(pending, a)
+		}
+	}
+	if len(pending) == 0 {
+		log.Printf("Nothing to roll back in %s", m.Path())
+		return
+	}
+
+	creds, err := loadCredentials(*token, cfg)
+	if err != nil {
+		log.Fatalf("Credentials: %v", err)
+	}
+	c := notesapi.NewClient(cfg.NotesURL, "")
+	limit, window, _ := parseRate(cfg.Rate)
+	c.Limiter = notesapi.NewTokenBucket(limit, window)
+	c.Logf = log.Printf
+	if err := authenticate(c, creds); err != nil {
+		log.Fatalf("Auth: %v", err)
+	}
+
+	failed := false
+	for _, a := range pending {
+		if creds.Email != "" && a.Email != "" && !strings.EqualFold(creds.Email, a.Email) {
+			log.Fatalf("Rollback: %s was imported into %s, but these credentials are for %s", m.Path(), a.Email, creds.Email)
+		}
+		if !*yes {
+			if err := confirmRollback(a, m); err != nil {
+				log.Fatalf("Rollback: %v", err)
+			}
+		}
+
+		r := manifest.Rollback(c, a, log.Printf)
+		log.Printf("Rolled back %s: %d notes deleted, %d attachments removed, %d tags deleted",
+			a, r.NotesDeleted, r.AttachmentsDeleted, r.TagsDeleted)
+		if len(r.NotUndone) > 0 {
+			log.Printf("%d change(s) were not undone:", len(r.NotUndone))
+			for _, s := range r.NotUndone {
+				log.Printf("  %s", s)
+			}
+		}
+		if len(r.Failed) > 0 {
+			failed = true
+			log.Printf("%d deletion(s) failed; run the rollback again to retry them:", len(r.Failed))
+			for _, s := range r.Failed {
+				log.Printf("  %s", s)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// confirmRollback asks on the terminal before anything is deleted.
+func confirmRollback(a *manifest.Account, m *manifest.Manifest) error {
+	if !credentials.IsTerminal(os.Stdin) {
+		return fmt.Errorf("refusing to delete without confirmation; pass --yes")
+	}
+	fmt.Fprintf(os.Stderr, "Permanently delete %s that the %s run of %s created in %s? [y/N] ",
+		a.Summary(), m.StartedAt.Local().Format("2006-01-02 15:04"), m.Tool, a)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read answer: %w", err)
+	}
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return fmt.Errorf("cancelled")
+	}
+	return nil
+}
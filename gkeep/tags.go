@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mbright/notesapi"
+)
+
+// defaultTagColor is the color of tags created for Keep labels, matching the
+// server's default gray.
+const defaultTagColor = notesapi.DefaultTagColor
+
+// keepColors maps Keep's note colors to the hex value Keep shows them in.
+var keepColors = map[string]string{
+	"RED":      "#f28b82",
+	"ORANGE":   "#fbbc04",
+	"YELLOW":   "#fff475",
+	"GREEN":    "#ccff90",
+	"TEAL":     "#a7ffeb",
+	"BLUE":     "#cbf0f8",
+	"CERULEAN": "#aecbfa",
+	"PURPLE":   "#d7aefb",
+	"PINK":     "#fdcfe8",
+	"BROWN":    "#e6c9a8",
+	"GRAY":     "#e8eaed",
+}
+
+// colorTagNames returns the tag name for each Keep color: keep-color/<color>
+// unless overridden by table. Table keys are Keep colors in any case; an
+// empty name means notes of that color get no tag.
+func colorTagNames(table map[string]string) map[string]string {
+	names := make(map[string]string, len(keepColors))
+	for color := range keepColors {
+		names[color] = "keep-color/" + strings.ToLower(color)
+	}
+	for color, name := range table {
+		names[strings.ToUpper(color)] = name
+	}
+	return names
+}
+
+// tagSpec is a tag a note should carry.
+type tagSpec struct {
+	name  string
+	color string
+}
+
+// noteTags returns the tags for a note: one per label, the reminder tag if it
+// had a reminder and, if colorTags is non-nil, one for its color. Names are
+// lowercased and not repeated.
+func noteTags(note KeepNote, colorTags map[string]string) []tagSpec {
+	var specs []tagSpec
+	seen := make(map[string]bool)
+	add := func(name, color string) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		specs = append(specs, tagSpec{name, color})
+	}
+
+	for _, l := range note.Labels {
+		add(l.Name, defaultTagColor)
+	}
+	if hasReminder(note) {
+		add(reminderTag, defaultTagColor)
+	}
+	if colorTags != nil {
+		color := strings.ToUpper(note.Color)
+		hex, ok := keepColors[color]
+		if !ok {
+			hex = defaultTagColor
+		}
+		add(colorTags[color], hex)
+	}
+	return specs
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// takeout is the Keep folder of a Google Takeout export, read from an
+// extracted folder or straight from the export's archives. Files belonging
+// to other Google products are never read.
+type takeout interface {
+	// notes returns the names of the note JSON files, sorted.
+	notes() []string
+	// open opens a file in the Keep folder by name.
+	open(name string) (io.ReadCloser, error)
+	// String describes where the export is read from.
+	String() string
+	Close() error
+}
+
+// openTakeout opens an extracted Takeout (or Keep) folder, a .zip archive,
+// or a .tgz/.tar.gz archive. Given one part of a split export such as
+// takeout-20240101T000000Z-001.zip, every sibling part is opened too.
+func openTakeout(p string) (takeout, error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("open takeout: %w", err)
+	}
+	if fi.IsDir() {
+		return dirTakeout(keepFolder(p)), nil
+	}
+
+	parts, err := archiveParts(p)
+	if err != nil {
+		return nil, err
+	}
+	switch lower := strings.ToLower(p); {
+	case strings.HasSuffix(lower, ".zip"):
+		return openZipTakeout(parts)
+	case strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar.gz"):
+		return openTarTakeout(parts)
+	}
+	return nil, fmt.Errorf("open takeout: %s is not a folder, .zip or .tgz", p)
+}
+
+// keepFolder returns the Keep folder of an extracted Takeout export. dir may
+// be the Takeout folder itself or the Keep folder inside it.
+func keepFolder(dir string) string {
+	if fi, err := os.Stat(filepath.Join(dir, "Keep")); err == nil && fi.IsDir() {
+		return filepath.Join(dir, "Keep")
+	}
+	return dir
+}
+
+// partSuffix matches the numbered suffix of a split Takeout archive.
+var partSuffix = regexp.MustCompile(`-\d{3}(\.zip|\.tgz|\.tar\.gz)$`)
+
+// archiveParts returns every part of the split export that p belongs to,
+// in order, or just p if it is not numbered.
+func archiveParts(p string) ([]string, error) {
+	dir, base := filepath.Split(p)
+	m := partSuffix.FindStringSubmatchIndex(base)
+	if m == nil {
+		return []string{p}, nil
+	}
+	prefix, ext := base[:m[0]], base[m[2]:m[3]]
+
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil, fmt.Errorf("list takeout parts: %w", err)
+	}
+	sibling := regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `-\d{3}` + regexp.QuoteMeta(ext) + `$`)
+	var parts []string
+	for _, e := range entries {
+		if sibling.MatchString(e.Name()) {
+			parts = append(parts, filepath.Join(dir, e.Name()))
+		}
+	}
+	slices.Sort(parts)
+	return parts, nil
+}
+
+// keepEntry reports whether an archive member lies directly in the Keep
+// folder, and returns its name within that folder.
+func keepEntry(member string) (string, bool) {
+	dir, name := path.Split(member)
+	dir = strings.TrimSuffix(dir, "/")
+	if name == "" || (dir != "Takeout/Keep" && dir != "Keep") {
+		return "", false
+	}
+	return name, true
+}
+
+// dirTakeout is an extracted Keep folder.
+type dirTakeout string
+
+func (d dirTakeout) notes() []string {
+	files, _ := filepath.Glob(filepath.Join(string(d), "*.json"))
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = filepath.Base(f)
+	}
+	return names
+}
+
+func (d dirTakeout) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), name))
+}
+
+func (d dirTakeout) String() string { return string(d) }
+func (d dirTakeout) Close() error   { return nil }
+
+// zipTakeout reads the Keep folder of one or more .zip parts in place.
+// Members are decompressed only when opened.
+type zipTakeout struct {
+	parts []*zip.ReadCloser
+	paths []string
+	files map[string]*zip.File
+}
+
+func openZipTakeout(paths []string) (*zipTakeout, error) {
+	z := &zipTakeout{paths: paths, files: make(map[string]*zip.File)}
+	for _, p := range paths {
+		r, err := zip.OpenReader(p)
+		if err != nil {
+			z.Close()
+			return nil, fmt.Errorf("open %s: %w", p, err)
+		}
+		z.parts = append(z.parts, r)
+		for _, f := range r.File {
+			if name, ok := keepEntry(f.Name); ok {
+				z.files[name] = f
+			}
+		}
+	}
+	return z, nil
+}
+
+func (z *zipTakeout) notes() []string {
+	var names []string
+	for name := range z.files {
+		if strings.HasSuffix(name, ".json") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (z *zipTakeout) open(name string) (io.ReadCloser, error) {
+	f, ok := z.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return f.Open()
+}
+
+func (z *zipTakeout) String() string { return strings.Join(z.paths, ", ") }
+
+func (z *zipTakeout) Close() error {
+	var errs []error
+	for _, r := range z.parts {
+		errs = append(errs, r.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// tarTakeout reads the Keep folder of one or more .tgz parts. A gzipped tar
+// cannot be read out of order, so the Keep folder — and nothing else — is
+// unpacked into a temporary directory that Close removes.
+type tarTakeout struct {
+	dirTakeout
+	paths []string
+}
+
+func openTarTakeout(paths []string) (*tarTakeout, error) {
+	tmp, err := os.MkdirTemp("", "gkeep-takeout-")
+	if err != nil {
+		return nil, fmt.Errorf("unpack takeout: %w", err)
+	}
+	t := &tarTakeout{dirTakeout: dirTakeout(tmp), paths: paths}
+	for _, p := range paths {
+		if err := unpackKeep(p, tmp); err != nil {
+			t.Close()
+			return nil, fmt.Errorf("unpack %s: %w", p, err)
+		}
+	}
+	return t, nil
+}
+
+// unpackKeep copies the Keep folder's regular files from a .tgz into dir.
+func unpackKeep(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := keepEntry(hdr.Name)
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		out, err := os.Create(filepath.Join(dir, filepath.Base(name)))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *tarTakeout) String() string { return strings.Join(t.paths, ", ") }
+
+func (t *tarTakeout) Close() error {
+	return os.RemoveAll(string(t.dirTakeout))
+}
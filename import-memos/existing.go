@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/provenance"
+)
+
+// existingNotes indexes the notes of a Notes account so that a rerun
+// without the journal can find the note an earlier run created from each
+// memo: by the memo name in their provenance, if they record one, and
+// otherwise by creation time. Notes are created with the memo's createTime,
+// which the server keeps to the second. Bodies are indexed without their
+// provenance.
+type existingNotes struct {
+	bySource  map[string][]notesapi.Note // memo name → notes
+	byCreated map[int64][]notesapi.Note  // Unix seconds → notes without provenance
+}
+
+// fetchExistingNotes lists every active, archived and trashed note of the
+// account.
+func fetchExistingNotes(c *notesapi.Client) (*existingNotes, error) {
+	e := &existingNotes{bySource: make(map[string][]notesapi.Note), byCreated: make(map[int64][]notesapi.Note)}
+	// A note archived or trashed while the pages are fetched can be listed
+	// twice.
+	seen := make(map[int]bool)
+	for _, filter := range []string{"", "archived", "trash"} {
+		opts := notesapi.ListNotesOptions{Filter: filter, ListOptions: notesapi.ListOptions{Limit: 100}}
+		for n, err := range c.AllNotes(opts) {
+			if err != nil {
+				return nil, fmt.Errorf("listing notes (filter=%q): %w", filter, err)
+			}
+			if seen[n.ID] {
+				continue
+			}
+			seen[n.ID] = true
+			p, imported := provenance.Parse(n.Body)
+			n.Body = provenance.Strip(n.Body)
+			switch {
+			case !imported:
+				sec := n.CreatedAt.Unix()
+				e.byCreated[sec] = append(e.byCreated[sec], n)
+			case p.Source == memosSource:
+				e.bySource[p.SourceID] = append(e.bySource[p.SourceID], n)
+			}
+			// Notes imported from elsewhere are never a memo's note.
+		}
+	}
+	return e, nil
+}
+
+// count returns the number of notes indexed.
+func (e *existingNotes) count() int {
+	n := 0
+	for _, notes := range e.byCreated {
+		n += len(notes)
+	}
+	for _, notes := range e.bySource {
+		n += len(notes)
+	}
+	return n
+}
+
+// match returns the note created from a memo, or nil if there is none, and
+// whether it still has the memo's title and body (its links to other notes
+// and to attachments aside). A note whose provenance names the memo is its
+// note, changed or not. Otherwise only a note without provenance created in
+// the same second with the same title and body is. If more than one note
+// qualifies, none is returned and ambiguous lists them all.
+func (e *existingNotes) match(memo MemosMemo, title, body string) (note *notesapi.Note, identical bool, ambiguous []int) {
+	same := func(n notesapi.Note) bool {
+		return sameText(n.Title, title) && sameText(comparableBody(n.Body), comparableBody(body))
+	}
+	if byName := e.bySource[memo.Name]; len(byName) > 0 {
+		if len(byName) > 1 {
+			return nil, false, noteIDs(byName)
+		}
+		return &byName[0], same(byName[0]), nil
+	}
+
+	var found []notesapi.Note
+	for _, n := range e.byCreated[memo.CreateTime.Unix()] {
+		if same(n) {
+			found = append(found, n)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, false, nil
+	case 1:
+		return &found[0], true, nil
+	}
+	return nil, false, noteIDs(found)
+}
+
+func noteIDs(notes []notesapi.Note) []int {
+	ids := make([]int, len(notes))
+	for i, n := range notes {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+// adoptNote returns a journal entry for a note that an earlier run created
+// from memo, so it can be updated and finished like a journaled one. The
+// memo's attachments whose filenames the note already has are marked as
+// uploaded, with the IDs they have in Notes.
+func adoptNote(c *notesapi.Client, memo MemosMemo, note *notesapi.Note) (*JournalEntry, error) {
+	entry := &JournalEntry{MemosUser: memo.Creator, NoteID: note.ID, Archived: note.Archived}
+	attachments := allAttachments(memo)
+	if len(attachments) == 0 {
+		return entry, nil
+	}
+	atts, err := c.ListAttachments(note.ID)
+	if err != nil {
+		return nil, err
+	}
+	var found []MemosAttachment
+	for _, a := range attachments {
+		for _, have := range atts {
+			if have.Filename == a.Filename {
+				if entry.Attachments == nil {
+					entry.Attachments = make(map[string]bool)
+				}
+				entry.Attachments[a.Name] = true
+				found = append(found, a)
+				break
+			}
+		}
+	}
+	if len(found) > 0 {
+		entry.AttachmentIDs = make(map[string]int)
+		matchAttachmentIDs(atts, found, entry.AttachmentIDs)
+	}
+	return entry, nil
+}
+
+// comparableBody returns the text of a note body that a rerun compares with
+// its memo, without the links the migration rewrites.
+func comparableBody(body string) string {
+	return withoutFileLinks(withoutReferences(body))
+}
+
+// sameText compares two texts ignoring differences in whitespace, such as
+// line endings the server normalized.
+func sameText(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
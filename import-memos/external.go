@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbright/notesapi"
+)
+
+// Ways of migrating attachments that Memos only links to, chosen with
+// --external-attachments.
+const (
+	// externalLink lists them as links in an "Attachments" section of the
+	// note.
+	externalLink = "link"
+	// externalFetch downloads them from where they are hosted and uploads
+	// them to the note like other attachments.
+	externalFetch = "fetch"
+)
+
+func checkExternalMode(mode string) error {
+	switch mode {
+	case externalLink, externalFetch:
+		return nil
+	}
+	return fmt.Errorf("invalid --external-attachments %q: want link or fetch", mode)
+}
+
+// isExternal reports whether Memos only links to an attachment instead of
+// storing it.
+func isExternal(a MemosAttachment) bool {
+	return a.ExternalLink != ""
+}
+
+// externalLinksSection renders the external attachments among atts as an
+// "Attachments" section of Markdown links, or returns "" if there are none.
+func externalLinksSection(atts []MemosAttachment) string {
+	var sb strings.Builder
+	for _, a := range atts {
+		if isExternal(a) {
+			fmt.Fprintf(&sb, "- [%s](%s)\n", linkLabel(a.Filename, a.ExternalLink), notesapi.EscapeLinkTarget(a.ExternalLink))
+		}
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "## Attachments\n\n" + sb.String()
+}
+
+// countExternal returns the number of external attachments among atts.
+func countExternal(atts []MemosAttachment) int {
+	n := 0
+	for _, a := range atts {
+		if isExternal(a) {
+			n++
+		}
+	}
+	return n
+}
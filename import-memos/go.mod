@@ -1,3 +1,7 @@
 module github.com/mbright/notes-import-memos
 
 go 1.23
+
+require github.com/mbright/notesapi v0.0.0
+
+replace github.com/mbright/notesapi => ../notesapi
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/credentials"
+	"github.com/mbright/notesapi/provenance"
+)
+
+// listImported implements the list-imported command, which prints every
+// note of a Notes account that records its provenance, from any importer.
+func listImported(args []string) error {
+	fs := flag.NewFlagSet("list-imported", flag.ExitOnError)
+	notesURL := fs.String("notes-url", "", "Base URL of the Notes instance (e.g. http://localhost:3000)")
+	notesToken := fs.String("notes-token", "", "Notes API token of the account to list (overrides NOTES_TOKEN)")
+	source := fs.String("source", "", "Only list notes from this source, e.g. memos or google-keep")
+	fs.Parse(args)
+
+	if *notesURL == "" {
+		return fmt.Errorf("--notes-url is required")
+	}
+	creds := sharedCredentials(*notesToken)
+	if creds == nil {
+		var err error
+		creds, err = promptCredentials(bufio.NewScanner(os.Stdin), *notesURL)
+		if err != nil {
+			return err
+		}
+		if creds == nil {
+			return fmt.Errorf("no Notes credentials given")
+		}
+	}
+
+	c := notesapi.NewClient(*notesURL, "")
+	c.Limiter = notesapi.NewTokenBucket(notesapi.DefaultRateLimit, notesapi.DefaultRateWindow)
+	if err := credentials.Login(c, *creds); err != nil {
+		return fmt.Errorf("authenticating %s: %w", creds, err)
+	}
+
+	notes, err := provenance.Find(c)
+	if err != nil {
+		return err
+	}
+	var shown []provenance.Note
+	for _, n := range notes {
+		if *source == "" || n.Provenance.Source == *source {
+			shown = append(shown, n)
+		}
+	}
+	if err := provenance.Write(os.Stdout, shown); err != nil {
+		return err
+	}
+	fmt.Printf("%d imported note(s)\n", len(shown))
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/mbright/notesapi/journal"
+)
+
+// JournalEntry records how far the migration of a single memo got.
+type JournalEntry struct {
+	MemosUser   string          `json:"memos_user"` // e.g. "users/1"
+	NoteID      int             `json:"note_id"`
+	Archived    bool            `json:"archived,omitempty"`
+	Attachments map[string]bool `json:"attachments,omitempty"` // attachment name → uploaded
+	// AttachmentIDs maps uploaded attachments to their IDs in Notes, which
+	// links to them in the note body point at.
+	AttachmentIDs map[string]int `json:"attachment_ids,omitempty"`
+	// FilesLinked records that every link in the note body to one of the
+	// memo's attachments points at Notes.
+	FilesLinked bool `json:"files_linked,omitempty"`
+	// Checksum identifies the memo content the note was last written from;
+	// --update rewrites the note when it changes.
+	Checksum string `json:"checksum,omitempty"`
+	// Links maps each memo the note links to, as a reference or the memo a
+	// comment is on, to the note ID the link points at.
+	Links  map[string]int  `json:"links,omitempty"`
+	Shares map[string]bool `json:"shares,omitempty"` // email → shared, or found to have no account
+	Done   bool            `json:"done"`
+}
+
+func (e *JournalEntry) clone() *JournalEntry {
+	c := *e
+	c.Attachments = maps.Clone(e.Attachments)
+	c.AttachmentIDs = maps.Clone(e.AttachmentIDs)
+	c.Links = maps.Clone(e.Links)
+	c.Shares = maps.Clone(e.Shares)
+	return &c
+}
+
+// memoChecksum returns a hex SHA-256 over the parts of a memo that are
+// written to its note: content, pin state, tags, folded comments, the memos
+// it links to and its reactions. A memo without comments, links or reactions
+// sums as it did before those were migrated.
+func memoChecksum(memo MemosMemo) string {
+	tags := slices.Sorted(slices.Values(memo.Tags))
+	var links []string
+	for _, ref := range outgoingRefs(memo) {
+		links = append(links, ref.name)
+	}
+	data, _ := json.Marshal(struct {
+		Content   string           `json:"content"`
+		Pinned    bool             `json:"pinned"`
+		Tags      []string         `json:"tags"`
+		Comments  []commentSummary `json:"comments,omitempty"`
+		Links     []string         `json:"links,omitempty"`
+		Reactions string           `json:"reactions,omitempty"`
+	}{memo.Content, memo.Pinned, tags, summarizeComments(memo.Comments), links,
+		reactionsSummary(memo.Reactions, func(name string) string { return name })})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// commentSummary is the part of a comment that memoChecksum covers.
+type commentSummary struct {
+	Creator string           `json:"creator"`
+	Content string           `json:"content"`
+	Replies []commentSummary `json:"replies,omitempty"`
+}
+
+func summarizeComments(comments []MemosMemo) []commentSummary {
+	var out []commentSummary
+	for _, c := range comments {
+		out = append(out, commentSummary{c.Creator, c.Content, summarizeComments(c.Comments)})
+	}
+	return out
+}
+
+// Journal checkpoints migration progress, keyed by memo name (e.g.
+// "memos/abc123"), so that an interrupted run can be resumed with --resume
+// and --update can find the note each memo became.
+type Journal = journal.Journal[JournalEntry]
+
+// OpenJournal loads the journal at path, or starts an empty one.
+func OpenJournal(path string) (*Journal, error) {
+	return journal.Open(path, (*JournalEntry).clone)
+}
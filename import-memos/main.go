@@ -6,37 +6,135 @@
 //	import-memos \
 //	  --memos-url http://localhost:8081 \
 //	  --memos-token <personal-access-token> \
-//	  --notes-url http://localhost:3000 \
-//	  [--dry-run]
+//	  --notes-url http://localhost:3000 [--notes-token <api-token>] \
+//	  [--mapping mapping.yaml] [--dry-run] [--resume] [--update] \
+//	  [--journal import-memos-journal.json] [--workers 4] \
+//	  [--provenance none|trailer|front-matter] [--manifest <file>] \
+//	  [--comments inline|notes|none] [--reactions] \
+//	  [--external-attachments link|fetch] \
+//	  [--share-with mapped,a@example.com] [--share-visibility protected|public]
+//
+//	import-memos list-imported --notes-url http://localhost:3000 [--source memos]
+//	import-memos rollback [--mapping mapping.yaml] [--yes] <manifest>
+//
+// Without --mapping, Notes credentials are prompted for each selected Memos
+// user, with the password hidden and taken from ~/.netrc when it has an
+// entry for the email given. If --notes-token, NOTES_TOKEN, or NOTES_EMAIL
+// and NOTES_PASSWORD are set (in that order of precedence), every selected
+// user is migrated into that one account without prompting.
+//
+// Reruns are safe: memos that already have a note are skipped. The note is
+// found through the journal, which every run reads and extends, or else
+// among the account's existing notes, by the memo name in their provenance
+// or by creation time, title and body. With --update, a memo whose
+// content, pin state or tags changed since is instead written over its note
+// with PATCH /api/v1/notes/:id, so the change shows up in the note's version
+// history instead of as a second note.
+//
+// With --provenance, each note records the memo it came from (source,
+// memo name, import time and importer version) as a trailer or a hidden
+// front-matter comment in its body, and is tagged imported/memos. The
+// list-imported command lists such notes, from this or any other importer.
+//
+// Comments on a memo are folded into its note as a threaded Comments
+// section, with each comment's author and time, or with --comments notes
+// become notes of their own that link to the memo's note. The memos a memo
+// references are listed under References as links to their notes; links
+// that cannot be made when a note is written, because the other memo has no
+// note yet, are added in a second pass once every memo is migrated.
+//
+// Each run records the notes, tags and attachments it created in a manifest
+// (--manifest). The rollback command deletes them again: created notes are
+// trashed and then purged, and created tags are deleted unless other notes
+// use them. Notes updated in place are reported, not reverted.
+//
+// With --reactions, a footer line summarizes each memo's reactions.
+//
+// Attachments that Memos only links to are listed as links in the note, or
+// with --external-attachments fetch downloaded and uploaded to it.
+//
+// Links in a memo to its own attachments (/file/attachments/<uid>/<name>)
+// are rewritten, once the files are uploaded, to the Notes attachments they
+// became, /notes/<id>/attachments/<id>.
+//
+// With --share-with, the notes of PROTECTED and PUBLIC memos (or, with
+// --share-visibility public, of PUBLIC memos only) are shared with the
+// Notes users listed, and tagged visibility/protected or visibility/public.
+// A rerun shares notes imported before with anyone newly listed.
 //
 // Limitations:
-//   - Memo relations, reactions, and comments are not migrated.
-//   - Memos visibility (PRIVATE/PROTECTED/PUBLIC) has no equivalent — all
-//     imported notes are private to the mapped Notes user.
-//   - Shares are not migrated.
+//   - Reactions on comments folded into a note are not shown.
+//   - References to memos migrated into another Notes account are listed
+//     but not linked.
+//   - Notes has no public notes: PUBLIC memos are only shared with the
+//     --share-with users, and without --share-with every note is private.
+//   - A memo's visibility is read when its note is written; shares are not
+//     revoked when a memo is made private later.
 //   - Tag colors default to #6b7280 (gray) since Memos tags have no color.
 //   - Attachments larger than 25 MB are skipped with a warning.
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/credentials"
+	"github.com/mbright/notesapi/manifest"
+	"github.com/mbright/notesapi/provenance"
 )
 
 const (
-	defaultTagColor          = "#6b7280"
-	maxAttachmentBytes int64 = 25 * 1024 * 1024 // 25 MB
+	defaultTagColor    = notesapi.DefaultTagColor
+	defaultJournalFile = "import-memos-journal.json"
+	// memosSource is the provenance source of notes imported from Memos.
+	memosSource = "memos"
 )
 
+var importerName = provenance.Importer("import-memos")
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "list-imported" {
+		if err := listImported(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rollback" {
+		if err := rollback(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	memosURL := flag.String("memos-url", "", "Base URL of the Memos instance (e.g. http://localhost:8081)")
 	memosToken := flag.String("memos-token", "", "Personal Access Token for the Memos instance")
 	notesURL := flag.String("notes-url", "", "Base URL of the Notes instance (e.g. http://localhost:3000)")
+	notesToken := flag.String("notes-token", "", "Notes API token to migrate every selected user into (overrides NOTES_TOKEN)")
 	delay := flag.Int("delay", 0, "Delay in milliseconds between Notes API calls (to avoid rate limiting)")
+	workers := flag.Int("workers", 1, "Number of memos to migrate in parallel")
+	mappingPath := flag.String("mapping", "", "JSON or YAML file mapping Memos users to Notes accounts (skips the interactive prompts)")
 	dryRun := flag.Bool("dry-run", false, "Print what would be done without writing to Notes")
+	resume := flag.Bool("resume", false, "Resume an interrupted migration from the journal, finishing half-imported memos")
+	journalPath := flag.String("journal", defaultJournalFile, "Path of the migration progress journal")
+	update := flag.Bool("update", false, "Rewrite notes whose memo changed since the journal recorded them (implies reading the journal)")
+	provenanceStyle := flag.String("provenance", provenance.None, "Record each note's origin in its body and tag it imported/memos: none, trailer or front-matter")
+	comments := flag.String("comments", commentsInline, "How to migrate comments: inline (a Comments section in the memo's note), notes (a note per comment) or none")
+	reactions := flag.Bool("reactions", false, "Summarize each memo's reactions in a footer of its note")
+	external := flag.String("external-attachments", externalLink, "How to migrate attachments Memos only links to: link (listed as links in the note) or fetch (downloaded and uploaded)")
+	shareWith := flag.String("share-with", "", "Comma-separated Notes emails to share PROTECTED and PUBLIC memos with; \"mapped\" stands for every mapped user")
+	shareVisibility := flag.String("share-visibility", shareProtected, "Least visible memos to share with --share-with: protected (PROTECTED and PUBLIC) or public (PUBLIC only)")
+	manifestPath := flag.String("manifest", manifest.DefaultPath("import-memos"), "File recording what this run creates, for import-memos rollback")
 	flag.Parse()
 
 	if *memosURL == "" || *memosToken == "" || *notesURL == "" {
@@ -44,6 +142,36 @@ func main() {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if err := provenance.CheckStyle(*provenanceStyle); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkCommentsMode(*comments); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := checkExternalMode(*external); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkShareVisibility(*shareVisibility); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	journal, err := OpenJournal(*journalPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *resume {
+		fmt.Printf("Resuming from %s (%d memo(s) recorded)\n", *journalPath, journal.Len())
+	} else if *update {
+		fmt.Printf("Updating notes recorded in %s (%d memo(s))\n", *journalPath, journal.Len())
+	} else if journal.Len() > 0 {
+		fmt.Printf("Skipping the %d memo(s) recorded in %s\n", journal.Len(), *journalPath)
+	}
 
 	memosClient := NewMemosClient(*memosURL, *memosToken)
 	fmt.Printf("Connecting to Memos at %s... ", *memosURL)
@@ -63,12 +191,27 @@ func main() {
 	}
 	fmt.Printf("Found %d user(s) in Memos\n", len(memosUsers))
 
-	// Interactive user mapping.
-	mappings, err := promptUserMappings(memosUsers, *notesURL)
+	// User mapping, from a file or interactively.
+	var mappings []UserMapping
+	if *mappingPath != "" {
+		mappings, err = loadUserMappings(*mappingPath, memosUsers, *notesURL)
+	} else {
+		mappings, err = promptUserMappings(memosUsers, *notesURL, sharedCredentials(*notesToken))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	shareEmails, err := parseShareWith(*shareWith, mappings)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	if len(shareEmails) > 0 {
+		fmt.Printf("Sharing %s memos with %s\n",
+			strings.Join(sharedVisibilities(*shareVisibility), " and "), strings.Join(shareEmails, ", "))
+	}
 
 	fmt.Printf("\nMigrating %d user(s)...\n", len(mappings))
 	apiDelay := time.Duration(*delay) * time.Millisecond
@@ -76,26 +219,150 @@ func main() {
 		fmt.Printf("Using %v delay between Notes API calls\n", apiDelay)
 	}
 
+	if *workers > 1 {
+		fmt.Printf("Using %d parallel workers\n", *workers)
+	}
+	var m *manifest.Manifest
+	if !*dryRun {
+		m, err = manifest.Create(*manifestPath, "import-memos", *notesURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Recording created notes in %s\n", m.Path())
+	}
+	opts := &migrateOptions{
+		dryRun:     *dryRun,
+		update:     *update,
+		resume:     *resume,
+		apiDelay:   apiDelay,
+		journal:    journal,
+		workers:    *workers,
+		provenance: *provenanceStyle,
+		importedAt: time.Now().UTC().Truncate(time.Second),
+		manifest:   m,
+		comments:   *comments,
+		users:      make(map[string]MemosUser, len(memosUsers)),
+		links:      newMemoLinks(),
+		external:   *external,
+		reactions:  *reactions,
+
+		shareWith:       shareEmails,
+		shareVisibility: *shareVisibility,
+	}
+	for _, u := range memosUsers {
+		opts.users[u.Name] = u
+	}
+	// One bucket for every mapped user: the server throttles per IP as well
+	// as per token.
+	limiter := notesapi.NewTokenBucket(notesapi.DefaultRateLimit, notesapi.DefaultRateWindow)
+
 	allStats := make(map[string]*MigrationStats)
-	for _, m := range mappings {
-		stats := migrateUser(memosClient, *notesURL, m, *dryRun, apiDelay)
-		allStats[m.MemosUsername] = stats
+	for i := range mappings {
+		mapping := &mappings[i]
+		notesClient := newNotesClient(*notesURL, mapping, mappings)
+		notesClient.Limiter = limiter
+		stats := migrateUser(memosClient, notesClient, mapping, opts)
+		allStats[mapping.MemosUsername] = stats
+	}
+	if !*dryRun {
+		linkReferences(opts, allStats)
 	}
 
 	// Print summary.
 	printSummary(allStats)
+	if m != nil {
+		fmt.Printf("\nManifest: %s (undo with: import-memos rollback %s)\n", m.Path(), m.Path())
+	}
+}
+
+// migrateOptions are the settings of a migration run that apply to every
+// user and memo.
+type migrateOptions struct {
+	dryRun   bool
+	update   bool
+	resume   bool
+	apiDelay time.Duration
+	journal  *Journal
+	workers  int
+	// provenance is the provenance style; importedAt is recorded with it.
+	provenance string
+	importedAt time.Time
+	// manifest records what the run creates, for rollback; nil on a dry run.
+	manifest *manifest.Manifest
+	// comments says how comments are migrated; users names their authors.
+	comments string
+	users    map[string]MemosUser // by name, e.g. "users/1"
+	// links resolves references between memos to links between notes.
+	links *memoLinks
+	// external says how attachments that Memos only links to are migrated.
+	external string
+	// reactions adds a summary of each memo's reactions to its note.
+	reactions bool
+	// shareWith lists the emails that memos at least as visible as
+	// shareVisibility are shared with.
+	shareWith       []string
+	shareVisibility string
+}
+
+// author names a Memos user in a byline: the display name, else the
+// username, else the user's resource name.
+func (o *migrateOptions) author(name string) string {
+	u, ok := o.users[name]
+	switch {
+	case !ok:
+		return name
+	case u.DisplayName != "":
+		return u.DisplayName
+	case u.Username != "":
+		return u.Username
+	}
+	return name
+}
+
+// provenanceOf returns the provenance to record for a memo.
+func (o *migrateOptions) provenanceOf(memo MemosMemo) provenance.Provenance {
+	return provenance.Provenance{
+		Source:     memosSource,
+		SourceID:   memo.Name,
+		ImportedAt: o.importedAt,
+		Importer:   importerName,
+	}
+}
+
+// sharedCredentials returns the Notes account that every prompted user is
+// migrated into, from --notes-token or else the NOTES_* environment
+// variables, or nil to prompt for each user.
+func sharedCredentials(token string) *credentials.Credentials {
+	if token != "" {
+		return &credentials.Credentials{Token: token, Source: "--notes-token"}
+	}
+	if c, ok := credentials.FromEnv(); ok {
+		return &c
+	}
+	return nil
 }
 
 // migrateUser performs the full migration for one Memos→Notes user mapping.
-func migrateUser(memosClient *MemosClient, notesURL string, mapping UserMapping, dryRun bool, apiDelay time.Duration) *MigrationStats {
+// Up to opts.workers memos are migrated in parallel; their output and stats are
+// still reported in memo order.
+func migrateUser(memosClient *MemosClient, notesClient *notesapi.Client, mapping *UserMapping, opts *migrateOptions) *MigrationStats {
 	stats := &MigrationStats{}
 	label := fmt.Sprintf("[%s]", mapping.MemosUsername)
-
-	notesClient := NewNotesClient(notesURL, mapping.NotesToken)
+	account := opts.manifest.Account(mapping.MemosUsername, mapping.NotesEmail)
 
 	fmt.Printf("\n%s Step 1/3: Syncing tags...\n", label)
 	fmt.Printf("%s   Fetching tag stats from Memos...\n", label)
-	tagMap, err := syncTags(memosClient, notesClient, mapping.MemosUserName, dryRun, stats, apiDelay)
+	var extraTags []string
+	if opts.provenance != provenance.None {
+		extraTags = append(extraTags, provenance.Tag(memosSource))
+	}
+	if len(opts.shareWith) > 0 {
+		for _, v := range sharedVisibilities(opts.shareVisibility) {
+			extraTags = append(extraTags, visibilityTag(v))
+		}
+	}
+	tagMap, err := syncTags(memosClient, notesClient, mapping.MemosUserName, extraTags, account, opts.dryRun, stats, opts.apiDelay)
 	if err != nil {
 		msg := fmt.Sprintf("tag sync failed: %v", err)
 		fmt.Printf("%s Error: %s\n", label, msg)
@@ -113,19 +380,83 @@ func migrateUser(memosClient *MemosClient, notesURL string, mapping UserMapping,
 		return stats
 	}
 
+	if opts.comments != commentsNone {
+		fmt.Printf("%s   Fetching comments...\n", label)
+		var n int
+		memos, n, err = withComments(memosClient, memos, opts.comments)
+		if err != nil {
+			msg := fmt.Sprintf("fetching comments failed: %v", err)
+			fmt.Printf("%s Error: %s\n", label, msg)
+			stats.Errors = append(stats.Errors, msg)
+			return stats
+		}
+		fmt.Printf("%s   Found %d comment(s)\n", label, n)
+	}
+
+	fmt.Printf("%s   Fetching existing notes from Notes...\n", label)
+	existing, err := fetchExistingNotes(notesClient)
+	if err != nil {
+		msg := fmt.Sprintf("fetching existing notes failed: %v", err)
+		fmt.Printf("%s Error: %s\n", label, msg)
+		stats.Errors = append(stats.Errors, msg)
+		return stats
+	}
+	fmt.Printf("%s   Found %d existing note(s)\n", label, existing.count())
+
 	fmt.Printf("\n%s Step 3/3: Importing %d memo(s) into Notes...\n", label, len(memos))
+	migration := &accountMigration{
+		opts:        opts,
+		memosClient: memosClient,
+		owner:       linkOwner{client: notesClient, account: mapping.notesAccount(), user: mapping.MemosUsername},
+		tagMap:      tagMap,
+		existing:    existing,
+		account:     account,
+	}
 
-	for i, memo := range memos {
-		progress := fmt.Sprintf("%s [%d/%d]", label, i+1, len(memos))
-		migrateOneMemo(memosClient, notesClient, memo, tagMap, progress, dryRun, stats, apiDelay)
+	type outcome struct {
+		out   bytes.Buffer
+		stats MigrationStats
 	}
+	notesapi.RunOrdered(len(memos), opts.workers, func(i int) *outcome {
+		o := &outcome{}
+		progress := fmt.Sprintf("%s [%d/%d]", label, i+1, len(memos))
+		migration.migrateMemo(&o.out, memos[i], progress, &o.stats)
+		return o
+	}, func(_ int, o *outcome) {
+		os.Stdout.Write(o.out.Bytes())
+		stats.add(&o.stats)
+	})
 
 	return stats
 }
 
-// syncTags ensures all Memos tags exist in the Notes instance and returns a
-// name→ID map.
-func syncTags(memosClient *MemosClient, notesClient *NotesClient, memosUserName string, dryRun bool, stats *MigrationStats, apiDelay time.Duration) (map[string]int, error) {
+// newNotesClient creates a Notes API client for a user mapping that reports
+// rate-limit retries inline with the progress output. When the client
+// refreshes its token, the new token is written back to every mapping that
+// shared the old one (several Memos users may map to one Notes account).
+func newNotesClient(notesURL string, mapping *UserMapping, all []UserMapping) *notesapi.Client {
+	c := notesapi.NewClient(notesURL, "")
+	c.SetToken(mapping.NotesToken, mapping.NotesTokenExpiresAt)
+	c.SetCredentials(mapping.NotesEmail, mapping.NotesPassword)
+	c.Logf = func(format string, args ...any) {
+		fmt.Printf("\n    "+format+"...", args...)
+	}
+	c.OnTokenRefresh = func(tok notesapi.Token) {
+		old := mapping.NotesToken
+		for i := range all {
+			if all[i].NotesToken == old {
+				all[i].NotesToken = tok.Token
+				all[i].NotesTokenExpiresAt = tok.ExpiresAt
+			}
+		}
+	}
+	return c
+}
+
+// syncTags ensures all Memos tags, and the extra tags the importer adds,
+// exist in the Notes instance and returns a name→ID map. Tags it creates are
+// recorded in account.
+func syncTags(memosClient *MemosClient, notesClient *notesapi.Client, memosUserName string, extra []string, account *manifest.Account, dryRun bool, stats *MigrationStats, apiDelay time.Duration) (map[string]int, error) {
 	// Get Memos tag names from user stats.
 	userStats, err := memosClient.GetUserStats(memosUserName)
 	if err != nil {
@@ -136,6 +467,7 @@ func syncTags(memosClient *MemosClient, notesClient *NotesClient, memosUserName
 	for name := range userStats.TagCount {
 		memosTagNames = append(memosTagNames, name)
 	}
+	memosTagNames = append(memosTagNames, extra...)
 
 	if dryRun {
 		tagMap := make(map[string]int)
@@ -147,36 +479,26 @@ func syncTags(memosClient *MemosClient, notesClient *NotesClient, memosUserName
 		return tagMap, nil
 	}
 
-	// Get existing Notes tags.
-	existingTags, err := notesClient.ListTags()
+	tags, err := notesClient.FetchTags()
 	if err != nil {
-		return nil, fmt.Errorf("listing Notes tags: %w", err)
+		return nil, err
 	}
-
-	tagMap := make(map[string]int)
-	for _, t := range existingTags {
-		tagMap[strings.ToLower(t.Name)] = t.ID
+	tags.OnCreate = func(tag notesapi.Tag) error {
+		stats.TagsCreated++
+		return account.TagCreated(tag.ID, tag.Name)
 	}
-
-	// Create missing tags.
 	for _, name := range memosTagNames {
-		lower := strings.ToLower(name)
-		if _, exists := tagMap[lower]; exists {
+		if _, exists := tags.ID(name); exists {
 			continue
 		}
-
 		if apiDelay > 0 {
 			time.Sleep(apiDelay)
 		}
-		tag, err := notesClient.CreateTag(name, defaultTagColor)
-		if err != nil {
-			return nil, fmt.Errorf("creating tag %q: %w", name, err)
+		if _, err := tags.Ensure(name, defaultTagColor); err != nil {
+			return nil, err
 		}
-		tagMap[strings.ToLower(tag.Name)] = tag.ID
-		stats.TagsCreated++
 	}
-
-	return tagMap, nil
+	return tags.IDs(), nil
 }
 
 // extractTitle splits the memo content into a title and body. If the content
@@ -195,111 +517,575 @@ func extractTitle(content string) (title, body string) {
 	return "", content
 }
 
-// migrateOneMemo creates a single note from a memo, including attachments.
-func migrateOneMemo(memosClient *MemosClient, notesClient *NotesClient, memo MemosMemo, tagMap map[string]int, progress string, dryRun bool, stats *MigrationStats, apiDelay time.Duration) {
+// accountMigration migrates memos into one Notes account. The workers of
+// migrateUser share it and do not change it.
+type accountMigration struct {
+	opts        *migrateOptions
+	memosClient *MemosClient
+	owner       linkOwner
+	tagMap      map[string]int
+	existing    *existingNotes
+	account     *manifest.Account
+}
+
+// memoMigration is the migration of one memo: the note it becomes, its
+// journal entry, and where its progress and stats go.
+type memoMigration struct {
+	*accountMigration
+	out      io.Writer
+	progress string
+	stats    *MigrationStats
+
+	memo        MemosMemo
+	title, desc string
+	// body is what existing notes are matched against; noteBody is the
+	// note's body as written, with its provenance.
+	body, noteBody string
+	attachments    []MemosAttachment
+	nListed        int // external attachments listed in the body
+	tagIDs         []int
+	maxSize        int
+	// links are the notes the References section links to, and linked
+	// whether it links every memo referenced.
+	links  map[string]int
+	linked bool
+	// unshared lists the emails the note is still to be shared with, so
+	// that a rerun with a wider policy shares notes imported before.
+	unshared []string
+
+	entry   *JournalEntry
+	sum     string
+	changed bool
+	// complete is cleared by a step that a rerun with --resume should retry.
+	complete bool
+}
+
+// migrateMemo creates a single note from a memo, including attachments.
+// Each completed step is recorded in the journal; a memo with a partial
+// journal entry has only its remaining steps performed. Progress is written
+// to out so that concurrent workers' output can be printed in memo order.
+//
+// A memo that the journal does not know of but that already has a note in
+// existing, from a run whose journal is gone, is skipped rather than
+// imported twice.
+//
+// With opts.update, a memo whose content changed since its note was written is
+// written over that note instead of being skipped.
+//
+// Folded comments are written after the memo's content, and links to the
+// notes of the memos it references last. A note whose links are incomplete
+// is kept in opts.links for the second pass.
+func (a *accountMigration) migrateMemo(out io.Writer, memo MemosMemo, progress string, stats *MigrationStats) {
+	m := a.newMemoMigration(out, memo, progress, stats)
+	if m.skipJournaled() || m.matchExisting() {
+		return
+	}
+	if a.opts.dryRun {
+		m.dryRun()
+		return
+	}
+	var ok bool
+	switch {
+	case m.changed:
+		ok = m.update()
+	case m.entry != nil:
+		ok = m.resume()
+	default:
+		ok = m.create()
+	}
+	if ok {
+		m.finish()
+	}
+}
+
+// newMemoMigration renders the note a memo becomes and looks up its journal
+// entry.
+func (a *accountMigration) newMemoMigration(out io.Writer, memo MemosMemo, progress string, stats *MigrationStats) *memoMigration {
+	opts := a.opts
+	m := &memoMigration{accountMigration: a, out: out, progress: progress, stats: stats}
+	if !opts.reactions {
+		// Left out of the checksum too, so turning them on rewrites with --update.
+		memo.Reactions = nil
+	}
+	m.memo = memo
+
 	title, body := extractTitle(memo.Content)
+	if memo.Parent != "" {
+		body = appendSection("Comment by "+byline(memo, opts.author), body)
+	}
+	if len(memo.Comments) > 0 {
+		body = appendSection(body, commentsSection(memo.Comments, opts.author))
+	}
+	// External attachments are either listed in the body or fetched with
+	// the others.
+	m.attachments = allAttachments(memo)
+	if opts.external == externalLink {
+		m.nListed = countExternal(m.attachments)
+		if m.nListed > 0 {
+			body = appendSection(body, externalLinksSection(m.attachments))
+		}
+	}
+	if footer := reactionsFooter(memo.Reactions, opts.author); footer != "" {
+		body = appendSection(body, footer)
+	}
+	var refs string
+	refs, m.links, m.linked = opts.links.resolve(memo, a.owner.account)
+	if refs != "" {
+		body = appendSection(body, refs)
+	}
+	m.title, m.body = title, body
 
 	// Resolve tag IDs.
-	var tagIDs []int
 	for _, t := range memo.Tags {
-		if id, ok := tagMap[strings.ToLower(t)]; ok {
-			tagIDs = append(tagIDs, id)
+		if id, ok := a.tagMap[strings.ToLower(t)]; ok {
+			m.tagIDs = append(m.tagIDs, id)
+		}
+	}
+	m.noteBody = body
+	if opts.provenance != provenance.None {
+		m.noteBody = provenance.Embed(body, opts.provenanceOf(memo), opts.provenance)
+		if id, ok := a.tagMap[provenance.Tag(memosSource)]; ok {
+			m.tagIDs = append(m.tagIDs, id)
+		}
+	}
+	shareWith := opts.recipients(memo, a.owner.account)
+	if opts.isShared(memo) {
+		if id, ok := a.tagMap[visibilityTag(memo.Visibility)]; ok {
+			m.tagIDs = append(m.tagIDs, id)
 		}
 	}
+	// Determine max_size: if body is longer than 32K, raise the limit.
+	if len(m.noteBody) > 32768 {
+		m.maxSize = len(m.noteBody) + 1024 // some headroom
+	}
 
-	nAttachments := len(memo.Attachments)
-	desc := title
-	if desc == "" {
-		desc = memo.Snippet
-		if len(desc) > 50 {
-			desc = desc[:50] + "..."
+	m.desc = title
+	if m.desc == "" {
+		m.desc = memo.Snippet
+		if len(m.desc) > 50 {
+			m.desc = m.desc[:50] + "..."
 		}
 	}
 
-	if dryRun {
-		fmt.Printf("  %s Would create note %q (%d tags, %d attachments, pinned=%v, archived=%v)\n",
-			progress, desc, len(tagIDs), nAttachments, memo.Pinned, memo.State == "ARCHIVED")
-		fmt.Printf("           Created: %s  Updated: %s\n", memo.CreateTime.Format("2006-01-02 15:04"), memo.UpdateTime.Format("2006-01-02 15:04"))
-		stats.NotesCreated++
+	m.entry = opts.journal.Get(memo.Name)
+	m.sum = memoChecksum(memo)
+	// Entries from before checksums were recorded have none, so --update
+	// counts them as changed; the checksum is recorded once the note has been
+	// rewritten.
+	m.changed = opts.update && m.entry != nil && m.entry.Checksum != m.sum
+
+	for _, email := range shareWith {
+		if m.entry == nil || !m.entry.Shares[email] {
+			m.unshared = append(m.unshared, email)
+		}
+	}
+	return m
+}
+
+// errorf reports an error that stops the memo's migration.
+func (m *memoMigration) errorf(format string, args ...any) {
+	m.report("Error", fmt.Sprintf(format, args...))
+}
+
+// warnf reports an error that the memo's migration carries on after.
+func (m *memoMigration) warnf(format string, args ...any) {
+	m.report("Warning", fmt.Sprintf(format, args...))
+}
+
+func (m *memoMigration) report(level, msg string) {
+	fmt.Fprintf(m.out, "  %s %s: %s\n", m.progress, level, msg)
+	m.stats.Errors = append(m.stats.Errors, msg)
+}
+
+// record flushes the journal after each completed step.
+func (m *memoMigration) record() bool {
+	if err := m.opts.journal.Record(m.memo.Name, m.entry); err != nil {
+		m.errorf("recording progress for memo %s: %v", m.memo.Name, err)
+		return false
+	}
+	return true
+}
+
+// track adds a change to the manifest.
+func (m *memoMigration) track(err error) bool {
+	if err != nil {
+		m.errorf("recording memo %s in the manifest: %v", m.memo.Name, err)
+		return false
+	}
+	return true
+}
+
+// noted records the memo's note for links from other memos, and keeps it
+// for the second pass if its own links are incomplete or out of date.
+func (m *memoMigration) noted(id int) {
+	m.opts.links.add(m.memo.Name, m.owner.account, id, m.title)
+	if !m.linked || !maps.Equal(m.links, m.entry.Links) {
+		m.opts.links.retry(linkJob{m.owner, m.memo})
+	}
+}
+
+// countContent counts what the note carries over besides the memo's text.
+func (m *memoMigration) countContent() {
+	m.stats.CommentsFolded += countComments(m.memo.Comments)
+	m.stats.ExternalLinked += m.nListed
+	m.stats.ReactionsKept += len(m.memo.Reactions)
+}
+
+// skipJournaled reports whether the journal's note for the memo is left as
+// it is: finished and unchanged, or unfinished without --resume.
+func (m *memoMigration) skipJournaled() bool {
+	entry := m.entry
+	switch {
+	case entry != nil && entry.Done && !m.changed && len(m.unshared) == 0:
+		fmt.Fprintf(m.out, "  %s Skipping %q (already imported as note #%d)\n", m.progress, m.desc, entry.NoteID)
+	case entry != nil && !entry.Done && !m.changed && !m.opts.resume && !m.opts.update:
+		fmt.Fprintf(m.out, "  %s Skipping %q (partly imported as note #%d; rerun with --resume to finish it)\n", m.progress, m.desc, entry.NoteID)
+	default:
+		return false
+	}
+	m.stats.NotesJournaled++
+	if !m.opts.dryRun {
+		m.noted(entry.NoteID)
+	}
+	return true
+}
+
+// matchExisting looks for the note of a memo the journal does not know of
+// among the account's notes. It reports whether the memo is skipped; with
+// --update, a changed note is adopted to be written over instead.
+func (m *memoMigration) matchExisting() bool {
+	if m.entry != nil {
+		return false
+	}
+	note, identical, ambiguous := m.existing.match(m.memo, m.title, m.body)
+	if len(ambiguous) > 0 {
+		m.warnf("memo %s matches notes %s in Notes; skipped, since it is unclear which is its note", m.memo.Name, joinIDs(ambiguous))
+		return true
+	}
+	if note == nil {
+		return false
+	}
+	switch {
+	case identical:
+		fmt.Fprintf(m.out, "  %s Skipping %q (already in Notes as note #%d)\n", m.progress, m.desc, note.ID)
+	case !m.opts.update || note.Trashed:
+		fmt.Fprintf(m.out, "  %s Skipping %q (already in Notes as note #%d, changed since; rerun with --update to rewrite it)\n", m.progress, m.desc, note.ID)
+	default:
+		entry, err := adoptNote(m.owner.client, m.memo, note)
+		if err != nil {
+			m.errorf("listing attachments of note %d: %v", note.ID, err)
+			return true
+		}
+		m.entry, m.changed = entry, true
+		return false
+	}
+	m.stats.NotesSkipped++
+	m.opts.links.add(m.memo.Name, m.owner.account, note.ID, m.title)
+	return true
+}
+
+// dryRun prints what migrating the memo would do.
+func (m *memoMigration) dryRun() {
+	memo, out := m.memo, m.out
+	switch {
+	case m.entry != nil && m.entry.Done && !m.changed:
+		fmt.Fprintf(out, "  %s Would share note #%d %q with %s\n", m.progress, m.entry.NoteID, m.desc, strings.Join(m.unshared, ", "))
+		m.stats.NotesResumed++
 		return
+	case m.changed:
+		fmt.Fprintf(out, "  %s Would update note #%d %q (%d tags, pinned=%v)\n", m.progress, m.entry.NoteID, m.desc, len(m.tagIDs), memo.Pinned)
+		if len(m.unshared) > 0 {
+			fmt.Fprintf(out, "           Share with: %s\n", strings.Join(m.unshared, ", "))
+		}
+		m.stats.NotesUpdated++
+	default:
+		fmt.Fprintf(out, "  %s Would create note %q (%d tags, %d attachments, pinned=%v, archived=%v)\n",
+			m.progress, m.desc, len(m.tagIDs), len(m.attachments)-m.nListed, memo.Pinned, memo.State == "ARCHIVED")
+		fmt.Fprintf(out, "           Created: %s  Updated: %s\n", memo.CreateTime.Format("2006-01-02 15:04"), memo.UpdateTime.Format("2006-01-02 15:04"))
+		if len(m.unshared) > 0 {
+			fmt.Fprintf(out, "           Share with: %s\n", strings.Join(m.unshared, ", "))
+		}
+		if n := len(linkedAttachments(m.body, m.attachments)); n > 0 {
+			fmt.Fprintf(out, "           Would point links to %d attachment(s) at Notes\n", n)
+		}
+		m.stats.NotesCreated++
 	}
+	m.countContent()
+}
 
-	fmt.Printf("  %s Creating note %q...", progress, desc)
+// update writes the memo over the note the journal records for it, and
+// reports whether its remaining steps can go ahead.
+func (m *memoMigration) update() bool {
+	entry := m.entry
+	fmt.Fprintf(m.out, "  %s Updating note #%d %q...", m.progress, entry.NoteID, m.desc)
+	if m.opts.apiDelay > 0 {
+		time.Sleep(m.opts.apiDelay)
+	}
+	tagIDs := m.tagIDs
+	if tagIDs == nil {
+		// Tags removed from the memo are removed from the note too.
+		tagIDs = []int{}
+	}
+	// Links to files uploaded before point at Notes straight away.
+	written, nLinked, filesLinked := rewriteResourceLinks(m.noteBody, entry.NoteID, entry.AttachmentIDs, m.attachments)
+	_, err := m.owner.client.UpdateNote(entry.NoteID, notesapi.NoteParams{
+		Title:     notesapi.String(m.title),
+		Body:      notesapi.String(written),
+		Pinned:    notesapi.Bool(m.memo.Pinned),
+		TagIDs:    tagIDs,
+		MaxSize:   m.maxSize,
+		UpdatedAt: m.memo.UpdateTime,
+	})
+	if err != nil {
+		m.errorf("updating note %d from memo %s: %v", entry.NoteID, m.memo.Name, err)
+		return false
+	}
+	m.stats.NotesUpdated++
+	m.countContent()
+	m.stats.FilesLinked += nLinked
+	if !m.track(m.account.NoteUpdated(entry.NoteID, m.memo.Name)) {
+		return false
+	}
+
+	entry.Checksum = m.sum
+	entry.Links = m.links
+	entry.FilesLinked = filesLinked
+	return m.record()
+}
+
+// resume picks up a note that a previous run created but did not finish
+// every step of.
+func (m *memoMigration) resume() bool {
+	fmt.Fprintf(m.out, "  %s Resuming note #%d %q...", m.progress, m.entry.NoteID, m.desc)
+	m.stats.NotesResumed++
+	return true
+}
+
+// create creates the memo's note and its journal entry, and reports whether
+// its remaining steps can go ahead.
+func (m *memoMigration) create() bool {
+	memo := m.memo
+	fmt.Fprintf(m.out, "  %s Creating note %q...", m.progress, m.desc)
 
 	// Delay to avoid rate limiting.
-	if apiDelay > 0 {
-		time.Sleep(apiDelay)
+	if m.opts.apiDelay > 0 {
+		time.Sleep(m.opts.apiDelay)
 	}
 
-	// Determine max_size: if body is longer than 32K, raise the limit.
-	maxSize := 0
-	if len(body) > 32768 {
-		maxSize = len(body) + 1024 // some headroom
+	note, err := m.owner.client.CreateNote(notesapi.NoteParams{
+		Title:     notesapi.String(m.title),
+		Body:      notesapi.String(m.noteBody),
+		Pinned:    notesapi.Bool(memo.Pinned),
+		TagIDs:    m.tagIDs,
+		MaxSize:   m.maxSize,
+		CreatedAt: memo.CreateTime,
+		UpdatedAt: memo.UpdateTime,
+	})
+	if err != nil {
+		m.errorf("creating note from memo %s: %v", memo.Name, err)
+		return false
 	}
+	m.stats.NotesCreated++
+	m.countContent()
 
-	note, err := notesClient.CreateNote(title, body, memo.Pinned, tagIDs, maxSize, memo.CreateTime, memo.UpdateTime)
-	if err != nil {
-		msg := fmt.Sprintf("creating note from memo %s: %v", memo.Name, err)
-		fmt.Printf("  %s Error: %s\n", progress, msg)
-		stats.Errors = append(stats.Errors, msg)
+	m.entry = &JournalEntry{MemosUser: memo.Creator, NoteID: note.ID, Checksum: m.sum, Links: m.links,
+		FilesLinked: len(linkedAttachments(m.body, m.attachments)) == 0}
+	return m.record() && m.track(m.account.NoteCreated(note.ID, memo.Name))
+}
+
+// finish performs the steps after the note is written that the journal
+// entry does not record as done: archiving, sharing, uploading attachments
+// and linking to them. The entry is marked done once all of them succeed.
+func (m *memoMigration) finish() {
+	m.complete = true
+	m.noted(m.entry.NoteID)
+	if !m.archive() || !m.share() || !m.uploadAttachments() || !m.linkFiles() {
 		return
 	}
-	stats.NotesCreated++
+	if m.complete {
+		m.entry.Done = true
+		if !m.record() {
+			return
+		}
+	}
 
-	// Archive if the memo was archived.
-	if memo.State == "ARCHIVED" {
-		fmt.Printf(" archiving...")
-		if apiDelay > 0 {
-			time.Sleep(apiDelay)
+	memo := m.memo
+	fmt.Fprintf(m.out, " done\n")
+	fmt.Fprintf(m.out, "           -> note #%d | %d tags, %d attachments | created %s, updated %s\n",
+		m.entry.NoteID, len(m.tagIDs), len(m.attachments)-m.nListed,
+		memo.CreateTime.Format("2006-01-02 15:04"), memo.UpdateTime.Format("2006-01-02 15:04"))
+}
+
+// archive archives the note if the memo was archived. Like the other steps
+// of finish, it reports false if the journal could not be written.
+func (m *memoMigration) archive() bool {
+	if m.memo.State != "ARCHIVED" || m.entry.Archived {
+		return true
+	}
+	fmt.Fprintf(m.out, " archiving...")
+	if m.opts.apiDelay > 0 {
+		time.Sleep(m.opts.apiDelay)
+	}
+	if _, err := m.owner.client.ArchiveNote(m.entry.NoteID); err != nil {
+		m.warnf("archiving note %d: %v", m.entry.NoteID, err)
+		m.complete = false
+		return true
+	}
+	m.entry.Archived = true
+	return m.record()
+}
+
+// share shares the note according to the memo's visibility.
+func (m *memoMigration) share() bool {
+	noteID, stats := m.entry.NoteID, m.stats
+	for _, email := range m.unshared {
+		if m.opts.apiDelay > 0 {
+			time.Sleep(m.opts.apiDelay)
 		}
-		if err := notesClient.ArchiveNote(note.ID); err != nil {
-			msg := fmt.Sprintf("archiving note %d: %v", note.ID, err)
-			fmt.Printf("  %s Warning: %s\n", progress, msg)
-			stats.Errors = append(stats.Errors, msg)
+		_, err := m.owner.client.CreateShare(noteID, email)
+		switch {
+		case errors.Is(err, notesapi.ErrNotFound):
+			fmt.Fprintf(m.out, " %s has no Notes account...", email)
+			if stats.UnmatchedShares == nil {
+				stats.UnmatchedShares = make(map[string]int)
+			}
+			stats.UnmatchedShares[email]++
+		case errors.Is(err, notesapi.ErrValidation):
+			// Already shared, or the email is the note's owner.
+		case err != nil:
+			m.warnf("sharing note %d with %s: %v", noteID, email, err)
+			m.complete = false
+			continue
+		default:
+			fmt.Fprintf(m.out, " shared with %s...", email)
+			stats.SharesCreated++
+		}
+		if m.entry.Shares == nil {
+			m.entry.Shares = make(map[string]bool)
+		}
+		m.entry.Shares[email] = true
+		if !m.record() {
+			return false
 		}
 	}
+	return true
+}
 
-	// Download and upload attachments.
-	if nAttachments > 0 {
-		fmt.Printf(" downloading %d attachment(s)...", nAttachments)
-		var files []FileData
-		for _, att := range memo.Attachments {
-			if int64(att.Size) > maxAttachmentBytes {
-				msg := fmt.Sprintf("skipping attachment %q (%d MB) — exceeds 25 MB limit", att.Filename, int64(att.Size)/(1024*1024))
-				fmt.Printf("  %s Warning: %s\n", progress, msg)
-				stats.Errors = append(stats.Errors, msg)
-				continue
-			}
+// uploadAttachments downloads and uploads the attachments not already
+// uploaded by a previous run.
+func (m *memoMigration) uploadAttachments() bool {
+	entry, memo := m.entry, m.memo
+	var pending []MemosAttachment
+	for _, att := range m.attachments {
+		if isExternal(att) && m.opts.external == externalLink {
+			continue
+		}
+		if !entry.Attachments[att.Name] {
+			pending = append(pending, att)
+		}
+	}
+	if len(pending) == 0 {
+		return true
+	}
 
-			fd, err := memosClient.DownloadAttachment(att.Name, att.Filename)
-			if err != nil {
-				msg := fmt.Sprintf("downloading attachment %q from memo %s: %v", att.Filename, memo.Name, err)
-				fmt.Printf("  %s Warning: %s\n", progress, msg)
-				stats.Errors = append(stats.Errors, msg)
-				continue
-			}
-			files = append(files, *fd)
+	fmt.Fprintf(m.out, " downloading %d attachment(s)...", len(pending))
+	var files []notesapi.File
+	var uploaded []string
+	fetched := 0
+	for _, att := range pending {
+		if int64(att.Size) > notesapi.MaxAttachmentBytes {
+			// Permanently unimportable, so it does not hold the memo open for --resume.
+			m.warnf("skipping attachment %q (%d MB) — exceeds 25 MB limit", att.Filename, int64(att.Size)/(1024*1024))
+			continue
 		}
 
-		if len(files) > 0 {
-			fmt.Printf(" uploading %d file(s)...", len(files))
-			if apiDelay > 0 {
-				time.Sleep(apiDelay)
-			}
-			if err := notesClient.UploadAttachments(note.ID, files); err != nil {
-				msg := fmt.Sprintf("uploading attachments to note %d: %v", note.ID, err)
-				fmt.Printf("  %s Warning: %s\n", progress, msg)
-				stats.Errors = append(stats.Errors, msg)
-			} else {
-				stats.AttachmentsUploaded += len(files)
+		if isExternal(att) {
+			fd, err := m.memosClient.DownloadExternalAttachment(att.ExternalLink, att.Filename)
+			switch {
+			case errors.Is(err, errAttachmentTooLarge), errors.Is(err, errUnsupportedLink):
+				// Retrying cannot help, so this does not hold back Done.
+				m.warnf("skipping external attachment %q: %v", att.Filename, err)
+			case err != nil:
+				m.warnf("fetching external attachment %q of memo %s: %v", att.Filename, memo.Name, err)
+				m.complete = false
+			default:
+				files = append(files, *fd)
+				uploaded = append(uploaded, att.Name)
+				fetched++
 			}
+			continue
+		}
+
+		fd, err := m.memosClient.DownloadAttachment(att.Name, att.Filename)
+		if err != nil {
+			m.warnf("downloading attachment %q from memo %s: %v", att.Filename, memo.Name, err)
+			m.complete = false
+			continue
 		}
+		files = append(files, *fd)
+		uploaded = append(uploaded, att.Name)
+	}
+	if len(files) == 0 {
+		return true
 	}
 
-	fmt.Printf(" done\n")
-	fmt.Printf("           -> note #%d | %d tags, %d attachments | created %s, updated %s\n",
-		note.ID, len(tagIDs), nAttachments,
-		memo.CreateTime.Format("2006-01-02 15:04"), memo.UpdateTime.Format("2006-01-02 15:04"))
+	fmt.Fprintf(m.out, " uploading %d file(s)...", len(files))
+	if m.opts.apiDelay > 0 {
+		time.Sleep(m.opts.apiDelay)
+	}
+	if err := m.owner.client.UploadAttachments(entry.NoteID, files); err != nil {
+		m.warnf("uploading attachments to note %d: %v", entry.NoteID, err)
+		m.complete = false
+		return true
+	}
+	m.stats.AttachmentsUploaded += len(files)
+	m.stats.ExternalFetched += fetched
+	if entry.Attachments == nil {
+		entry.Attachments = make(map[string]bool)
+	}
+	for _, name := range uploaded {
+		entry.Attachments[name] = true
+	}
+	if !m.record() {
+		return false
+	}
+	for _, f := range files {
+		if !m.track(m.account.AttachmentUploaded(entry.NoteID, f.Filename)) {
+			return false
+		}
+	}
+	return true
+}
+
+// linkFiles points the body's links to the memo's files at the attachments
+// they were uploaded as. A memo changed since its note was written is left
+// to --update.
+func (m *memoMigration) linkFiles() bool {
+	entry := m.entry
+	if entry.FilesLinked || entry.Checksum != m.sum {
+		return true
+	}
+	n, err := linkFiles(m.owner.client, entry, m.noteBody, m.attachments, m.maxSize, m.memo.UpdateTime, m.opts.apiDelay)
+	if err != nil {
+		m.warnf("linking attachments in note %d: %v", entry.NoteID, err)
+		m.complete = false
+		return true
+	}
+	if n > 0 {
+		fmt.Fprintf(m.out, " linked %d attachment(s)...", n)
+		m.stats.FilesLinked += n
+		entry.Links = m.links
+	}
+	return m.record()
+}
+
+// joinIDs lists note IDs as "#1, #2".
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(s, ", ")
 }
 
 // printSummary prints a final summary of the migration.
@@ -309,15 +1095,55 @@ func printSummary(allStats map[string]*MigrationStats) {
 	fmt.Println("========================================")
 
 	totalNotes := 0
+	totalSkipped := 0
 	totalTags := 0
 	totalAttachments := 0
 	totalErrors := 0
 
-	for user, s := range allStats {
+	// Report users in a stable order.
+	for _, user := range slices.Sorted(maps.Keys(allStats)) {
+		s := allStats[user]
 		fmt.Printf("\n  User: %s\n", user)
 		fmt.Printf("    Notes created:       %d\n", s.NotesCreated)
+		if s.NotesUpdated > 0 {
+			fmt.Printf("    Notes updated:       %d\n", s.NotesUpdated)
+		}
+		if s.NotesResumed > 0 || s.NotesJournaled > 0 {
+			fmt.Printf("    Notes resumed:       %d\n", s.NotesResumed)
+			fmt.Printf("    Already imported:    %d\n", s.NotesJournaled)
+		}
+		if s.NotesSkipped > 0 {
+			fmt.Printf("    Already in Notes:    %d\n", s.NotesSkipped)
+		}
 		fmt.Printf("    Tags created:        %d\n", s.TagsCreated)
 		fmt.Printf("    Attachments uploaded: %d\n", s.AttachmentsUploaded)
+		if s.FilesLinked > 0 {
+			fmt.Printf("    Attachment links:    %d\n", s.FilesLinked)
+		}
+		if s.ExternalLinked > 0 {
+			fmt.Printf("    External links:      %d\n", s.ExternalLinked)
+		}
+		if s.ExternalFetched > 0 {
+			fmt.Printf("    External fetched:    %d\n", s.ExternalFetched)
+		}
+		if s.CommentsFolded > 0 {
+			fmt.Printf("    Comments folded:     %d\n", s.CommentsFolded)
+		}
+		if s.ReactionsKept > 0 {
+			fmt.Printf("    Reactions kept:      %d\n", s.ReactionsKept)
+		}
+		if s.NotesLinked > 0 {
+			fmt.Printf("    Notes relinked:      %d\n", s.NotesLinked)
+		}
+		if s.SharesCreated > 0 {
+			fmt.Printf("    Shares created:      %d\n", s.SharesCreated)
+		}
+		if len(s.UnmatchedShares) > 0 {
+			fmt.Printf("    Not shared (no Notes account):\n")
+			for _, email := range slices.Sorted(maps.Keys(s.UnmatchedShares)) {
+				fmt.Printf("      - %s (%d note(s))\n", email, s.UnmatchedShares[email])
+			}
+		}
 		if len(s.Errors) > 0 {
 			fmt.Printf("    Errors:              %d\n", len(s.Errors))
 			for _, e := range s.Errors {
@@ -325,6 +1151,7 @@ func printSummary(allStats map[string]*MigrationStats) {
 			}
 		}
 		totalNotes += s.NotesCreated
+		totalSkipped += s.NotesSkipped + s.NotesJournaled
 		totalTags += s.TagsCreated
 		totalAttachments += s.AttachmentsUploaded
 		totalErrors += len(s.Errors)
@@ -332,6 +1159,9 @@ func printSummary(allStats map[string]*MigrationStats) {
 
 	fmt.Println("\n  ──────────────────────────────────")
 	fmt.Printf("  Totals: %d notes, %d tags, %d attachments", totalNotes, totalTags, totalAttachments)
+	if totalSkipped > 0 {
+		fmt.Printf(", %d skipped", totalSkipped)
+	}
 	if totalErrors > 0 {
 		fmt.Printf(", %d errors", totalErrors)
 	}
@@ -6,12 +6,17 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/credentials"
 )
 
 // promptUserMappings interactively prompts the operator to map Memos users to
-// Notes users by providing a Notes API token for each Memos user they want to
-// migrate.
-func promptUserMappings(memosUsers []MemosUser, notesURL string) ([]UserMapping, error) {
+// Notes users by providing Notes credentials for each Memos user they want to
+// migrate. If shared is non-nil (from --notes-token or NOTES_* environment
+// variables), every selected user is mapped to that one account instead.
+func promptUserMappings(memosUsers []MemosUser, notesURL string, shared *credentials.Credentials) ([]UserMapping, error) {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	fmt.Println("\n=== Memos Users ===")
@@ -64,33 +69,32 @@ func promptUserMappings(memosUsers []MemosUser, notesURL string) ([]UserMapping,
 	var mappings []UserMapping
 	for _, mu := range selectedUsers {
 		fmt.Printf("\nMapping Memos user: %s (%s)\n", mu.DisplayName, mu.Username)
-		fmt.Println("  Enter Notes credentials for this user's account.")
-
-		fmt.Print("  Email: ")
-		if !scanner.Scan() {
-			return nil, fmt.Errorf("no input received")
-		}
-		email := strings.TrimSpace(scanner.Text())
-
-		fmt.Print("  Password: ")
-		if !scanner.Scan() {
-			return nil, fmt.Errorf("no input received")
-		}
-		password := strings.TrimSpace(scanner.Text())
 
-		if email == "" || password == "" {
-			fmt.Println("  Skipping (empty credentials)")
-			continue
+		var creds credentials.Credentials
+		if shared != nil {
+			creds = *shared
+			fmt.Printf("  Using %s\n", creds)
+		} else {
+			c, err := promptCredentials(scanner, notesURL)
+			if err != nil {
+				return nil, err
+			}
+			if c == nil {
+				fmt.Println("  Skipping (empty credentials)")
+				continue
+			}
+			creds = *c
 		}
 
-		client := NewNotesClient(notesURL, "")
-		token, err := client.Authenticate(email, password)
-		if err != nil {
+		client := notesapi.NewClient(notesURL, "")
+		if err := credentials.Login(client, creds); err != nil {
 			fmt.Printf("  Error: authentication failed: %v\n", err)
 			fmt.Println("  Skipping this user")
 			continue
 		}
-		fmt.Println("  ✓ Authenticated successfully")
+		if creds.Token == "" {
+			fmt.Println("  ✓ Authenticated successfully")
+		}
 
 		// Verify the token works with an authenticated endpoint.
 		if err := client.Ping(); err != nil {
@@ -108,10 +112,13 @@ func promptUserMappings(memosUsers []MemosUser, notesURL string) ([]UserMapping,
 		}
 
 		mappings = append(mappings, UserMapping{
-			MemosUserName:    mu.Name,
-			MemosUsername:     mu.Username,
-			MemosDisplayName: mu.DisplayName,
-			NotesToken:       token,
+			MemosUserName:       mu.Name,
+			MemosUsername:       mu.Username,
+			MemosDisplayName:    mu.DisplayName,
+			NotesEmail:          creds.Email,
+			NotesPassword:       creds.Password,
+			NotesToken:          client.Token(),
+			NotesTokenExpiresAt: client.TokenExpiresAt(),
 		})
 	}
 
@@ -121,3 +128,159 @@ func promptUserMappings(memosUsers []MemosUser, notesURL string) ([]UserMapping,
 
 	return mappings, nil
 }
+
+// promptCredentials asks for one Notes account. The password is taken from a
+// matching ~/.netrc entry when there is one, and otherwise read without echo.
+// Leaving the email blank asks for an API token instead. It returns nil if
+// the operator entered nothing.
+func promptCredentials(scanner *bufio.Scanner, notesURL string) (*credentials.Credentials, error) {
+	fmt.Println("  Enter Notes credentials for this user's account (leave the email blank to use an API token).")
+
+	fmt.Print("  Email: ")
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("no input received")
+	}
+	email := strings.TrimSpace(scanner.Text())
+
+	if email == "" {
+		fmt.Print("  API token: ")
+		token, err := readSecret(scanner)
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, nil
+		}
+		return &credentials.Credentials{Token: token, Source: "prompt"}, nil
+	}
+
+	c, ok, err := credentials.FromNetrc(notesURL, email)
+	if err != nil {
+		fmt.Printf("  Warning: %v\n", err)
+	}
+	if ok {
+		fmt.Printf("  Using password from %s\n", c.Source)
+		return &c, nil
+	}
+
+	fmt.Print("  Password: ")
+	password, err := readSecret(scanner)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, nil
+	}
+	return &credentials.Credentials{Email: email, Password: password, Source: "prompt"}, nil
+}
+
+// readSecret reads a password or token without echo when stdin is a
+// terminal. Piped input is read through scanner, which may already have
+// buffered it.
+func readSecret(scanner *bufio.Scanner) (string, error) {
+	if credentials.IsTerminal(os.Stdin) {
+		s, err := credentials.ReadPassword(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+		return strings.TrimSpace(s), nil
+	}
+	if !scanner.Scan() {
+		return "", fmt.Errorf("no input received")
+	}
+	return strings.TrimSpace(scanner.Text()), nil
+}
+
+// loadUserMappings builds user mappings from a --mapping file without any
+// prompting. Every entry is validated up front — the Memos user exists and is
+// active, its credentials resolve, authenticate, and reach the Notes API —
+// and if any entry fails, a report is printed and nothing is migrated.
+func loadUserMappings(path string, memosUsers []MemosUser, notesURL string) ([]UserMapping, error) {
+	mf, err := readMappingFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	byUsername := make(map[string]MemosUser, len(memosUsers))
+	for _, u := range memosUsers {
+		byUsername[u.Username] = u
+	}
+
+	fmt.Printf("\nValidating %d mapping(s) from %s...\n", len(mf.Users), path)
+	seen := make(map[string]bool)
+	var mappings []UserMapping
+	failed := 0
+	for i, e := range mf.Users {
+		label := e.MemosUsername
+		if label == "" {
+			label = fmt.Sprintf("entry %d", i+1)
+		}
+
+		m, err := validateMapping(e, byUsername, seen, notesURL)
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", label, err)
+			failed++
+			continue
+		}
+		target := m.NotesEmail
+		if target == "" {
+			target = "(token)"
+		}
+		fmt.Printf("  ✓ %s → %s\n", label, target)
+		mappings = append(mappings, *m)
+	}
+
+	if failed > 0 {
+		return nil, fmt.Errorf("%d of %d mapping(s) failed validation; nothing was migrated", failed, len(mf.Users))
+	}
+	return mappings, nil
+}
+
+// validateMapping checks one mapping file entry and returns the resulting
+// mapping with an authenticated token.
+func validateMapping(e MappingEntry, byUsername map[string]MemosUser, seen map[string]bool, notesURL string) (*UserMapping, error) {
+	if e.MemosUsername == "" {
+		return nil, fmt.Errorf("memos_username is required")
+	}
+	if seen[e.MemosUsername] {
+		return nil, fmt.Errorf("Memos user is mapped more than once")
+	}
+	seen[e.MemosUsername] = true
+
+	mu, ok := byUsername[e.MemosUsername]
+	if !ok {
+		return nil, fmt.Errorf("no such Memos user")
+	}
+	if mu.State != "" && mu.State != "NORMAL" {
+		return nil, fmt.Errorf("Memos user is not active (state %s)", mu.State)
+	}
+
+	password, token, err := e.resolveCredentials(notesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := notesapi.NewClient(notesURL, "")
+	if password != "" {
+		tok, err := client.Authenticate(e.NotesEmail, password)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+		token = tok.Token
+	} else {
+		client.SetToken(token, time.Time{})
+	}
+	if err := client.Ping(); err != nil {
+		return nil, fmt.Errorf("could not verify Notes API connection: %w", err)
+	}
+
+	return &UserMapping{
+		MemosUserName:       mu.Name,
+		MemosUsername:       mu.Username,
+		MemosDisplayName:    mu.DisplayName,
+		NotesEmail:          e.NotesEmail,
+		NotesPassword:       password,
+		NotesToken:          token,
+		NotesTokenExpiresAt: client.TokenExpiresAt(),
+	}, nil
+}
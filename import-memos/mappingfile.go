@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mbright/notesapi/credentials"
+)
+
+// MappingEntry maps one Memos user to a Notes account in a --mapping file.
+// Exactly one credential source is required: a password (literal or from an
+// environment variable, together with notes_email) or a pre-issued token. An
+// entry with only notes_email takes its password from ~/.netrc.
+type MappingEntry struct {
+	MemosUsername    string `json:"memos_username"`
+	NotesEmail       string `json:"notes_email"`
+	NotesPassword    string `json:"notes_password"`
+	NotesPasswordEnv string `json:"notes_password_env"`
+	NotesToken       string `json:"notes_token"`
+	NotesTokenEnv    string `json:"notes_token_env"`
+}
+
+// MappingFile is the top-level structure of a --mapping file.
+type MappingFile struct {
+	Users []MappingEntry `json:"users"`
+}
+
+// readMappingFile parses a JSON or YAML mapping file, chosen by extension.
+//
+// YAML support covers the shape of the JSON format only: a top-level "users"
+// list whose items are flat "key: value" maps of strings, plain or in single
+// or double quotes, with "#" comments. For example:
+//
+//	users:
+//	  - memos_username: alice
+//	    notes_email: alice@example.com
+//	    notes_password_env: ALICE_NOTES_PASSWORD
+//	  - memos_username: bob
+//	    notes_token_env: BOB_NOTES_TOKEN
+//
+// Anything else, such as flow collections, block scalars, anchors, tags or
+// nested maps, is rejected with an error rather than misread.
+func readMappingFile(path string) (*MappingFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mapping file: %w", err)
+	}
+
+	var mf MappingFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&mf); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		if err := parseYAMLMappings(data, &mf); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("mapping file %s must end in .json, .yaml or .yml", path)
+	}
+
+	if len(mf.Users) == 0 {
+		return nil, fmt.Errorf("mapping file %s lists no users", path)
+	}
+	return &mf, nil
+}
+
+// parseYAMLMappings parses the YAML subset described on readMappingFile.
+func parseYAMLMappings(data []byte, mf *MappingFile) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	inUsers := false
+	var cur *MappingEntry
+	var seen map[string]bool // keys of the current item
+	itemCol, keyCol := -1, -1
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line, err := stripYAMLComment(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || lineNo == 1 && line == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.HasPrefix(line[indent:], "\t") {
+			return fmt.Errorf("line %d: tabs are not allowed in YAML indentation", lineNo)
+		}
+		trimmed := line[indent:]
+
+		if indent == 0 && !strings.HasPrefix(trimmed, "-") {
+			if trimmed != "users:" {
+				return fmt.Errorf("line %d: expected top-level \"users:\" followed by a list, got %q", lineNo, trimmed)
+			}
+			if inUsers {
+				return fmt.Errorf("line %d: \"users\" is given twice", lineNo)
+			}
+			inUsers = true
+			continue
+		}
+		if !inUsers {
+			return fmt.Errorf("line %d: expected top-level \"users:\" first", lineNo)
+		}
+
+		col := indent
+		if rest, ok := strings.CutPrefix(trimmed, "-"); ok && (rest == "" || rest[0] == ' ') {
+			if itemCol >= 0 && indent != itemCol {
+				return fmt.Errorf("line %d: list items must line up; nested lists are not supported", lineNo)
+			}
+			itemCol = indent
+			mf.Users = append(mf.Users, MappingEntry{})
+			cur = &mf.Users[len(mf.Users)-1]
+			seen = make(map[string]bool)
+			keyCol = -1
+			col += 1 + len(rest) - len(strings.TrimLeft(rest, " "))
+			trimmed = strings.TrimLeft(rest, " ")
+			if trimmed == "" {
+				continue
+			}
+		}
+		if cur == nil {
+			return fmt.Errorf("line %d: expected a list item (\"- key: value\")", lineNo)
+		}
+		if keyCol < 0 {
+			keyCol = col
+		}
+		if col != keyCol {
+			return fmt.Errorf("line %d: keys of a list item must line up; nested maps are not supported", lineNo)
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok || value != "" && value[0] != ' ' {
+			return fmt.Errorf("line %d: expected \"key: value\"", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		if seen[key] {
+			return fmt.Errorf("line %d: key %q is given twice", lineNo, key)
+		}
+		seen[key] = true
+		value, err = yamlScalar(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+		if err := setMappingField(cur, key, value); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// stripYAMLComment removes a "#" comment that is outside quotes. In single
+// quotes, two quotes stand for one; in double quotes, a backslash escapes
+// the next character.
+func stripYAMLComment(line string) (string, error) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || line[i-1] == ' '):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i], nil
+		}
+	}
+	if quote != 0 {
+		return "", fmt.Errorf("unterminated %c-quoted string", quote)
+	}
+	return line, nil
+}
+
+// yamlScalar returns the string a scalar value stands for: the text of a
+// plain scalar, or a single- or double-quoted one unescaped by YAML's rules.
+// "~" and "null" are YAML's null, read as an empty string. Values that YAML
+// would read as something other than a one-line string are rejected.
+func yamlScalar(v string) (string, error) {
+	switch {
+	case v == "" || v == "~" || v == "null" || v == "Null" || v == "NULL":
+		return "", nil
+	case v[0] == '"':
+		return unquoteYAMLDouble(v)
+	case v[0] == '\'':
+		return unquoteYAMLSingle(v)
+	case strings.ContainsRune("[]{}&*!|>%@`,", rune(v[0])):
+		return "", fmt.Errorf("%q is not a plain string; quote it, as only strings are supported", v)
+	case v[0] == '-' && (len(v) == 1 || v[1] == ' '), v[0] == '?' && (len(v) == 1 || v[1] == ' '):
+		return "", fmt.Errorf("%q is not a plain string; quote it, as only strings are supported", v)
+	case strings.Contains(v, ": "):
+		return "", fmt.Errorf("%q holds \": \"; quote it, as nested maps are not supported", v)
+	}
+	return v, nil
+}
+
+// unquoteYAMLSingle unquotes a single-quoted scalar, in which a doubled
+// quote is the only escape.
+func unquoteYAMLSingle(v string) (string, error) {
+	if len(v) < 2 || v[len(v)-1] != '\'' {
+		return "", fmt.Errorf("text after the closing quote of %s", v)
+	}
+	inner := v[1 : len(v)-1]
+	if strings.Contains(strings.ReplaceAll(inner, "''", ""), "'") {
+		return "", fmt.Errorf("text after the closing quote of %s", v)
+	}
+	return strings.ReplaceAll(inner, "''", "'"), nil
+}
+
+// yamlEscapes are the one-character escapes of YAML double-quoted scalars.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v",
+	'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// unquoteYAMLDouble unquotes a double-quoted scalar by YAML's escape rules,
+// which differ from Go's: "\x" takes two hex digits and means a code point,
+// not a byte, and escapes such as "\e", "\N" and "\_" exist only in YAML.
+func unquoteYAMLDouble(v string) (string, error) {
+	var sb strings.Builder
+	for i := 1; i < len(v); i++ {
+		c := v[i]
+		switch c {
+		case '"':
+			if i != len(v)-1 {
+				return "", fmt.Errorf("text after the closing quote of %s", v)
+			}
+			return sb.String(), nil
+		case '\\':
+			i++
+			if i >= len(v) {
+				break
+			}
+			if s, ok := yamlEscapes[v[i]]; ok {
+				sb.WriteString(s)
+				continue
+			}
+			n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[v[i]]
+			if n == 0 {
+				return "", fmt.Errorf("unknown escape \\%c in %s", v[i], v)
+			}
+			if i+n >= len(v) {
+				return "", fmt.Errorf("short \\%c escape in %s", v[i], v)
+			}
+			r, err := strconv.ParseUint(v[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", fmt.Errorf("invalid \\%c escape in %s", v[i], v)
+			}
+			sb.WriteRune(rune(r))
+			i += n
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string %s", v)
+}
+
+func setMappingField(e *MappingEntry, key, value string) error {
+	switch key {
+	case "memos_username":
+		e.MemosUsername = value
+	case "notes_email":
+		e.NotesEmail = value
+	case "notes_password":
+		e.NotesPassword = value
+	case "notes_password_env":
+		e.NotesPasswordEnv = value
+	case "notes_token":
+		e.NotesToken = value
+	case "notes_token_env":
+		e.NotesTokenEnv = value
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// resolveCredentials returns the entry's password or token, reading
+// environment variable references and, failing those, a ~/.netrc entry for
+// notes_email on the Notes host.
+func (e MappingEntry) resolveCredentials(notesURL string) (password, token string, err error) {
+	password, token = e.NotesPassword, e.NotesToken
+	if e.NotesPasswordEnv != "" {
+		password = os.Getenv(e.NotesPasswordEnv)
+		if password == "" {
+			return "", "", fmt.Errorf("environment variable %s is not set", e.NotesPasswordEnv)
+		}
+	}
+	if e.NotesTokenEnv != "" {
+		token = os.Getenv(e.NotesTokenEnv)
+		if token == "" {
+			return "", "", fmt.Errorf("environment variable %s is not set", e.NotesTokenEnv)
+		}
+	}
+
+	if password == "" && token == "" && e.NotesEmail != "" {
+		c, ok, err := credentials.FromNetrc(notesURL, e.NotesEmail)
+		if err != nil {
+			return "", "", err
+		}
+		if ok {
+			password = c.Password
+		}
+	}
+
+	switch {
+	case password != "" && token != "":
+		return "", "", fmt.Errorf("give either a password or a token, not both")
+	case password != "" && e.NotesEmail == "":
+		return "", "", fmt.Errorf("notes_email is required with a password")
+	case password == "" && token == "" && e.NotesEmail != "":
+		return "", "", fmt.Errorf("no password or token given, and no %s entry for %s", credentials.NetrcPath(), e.NotesEmail)
+	case password == "" && token == "":
+		return "", "", fmt.Errorf("no password or token given")
+	}
+	return password, token, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAMLMappings(t *testing.T) {
+	tests := []struct {
+		name, yaml string
+		want       []MappingEntry
+	}{
+		{
+			name: "plain",
+			yaml: `users:
+  - memos_username: alice
+    notes_email: alice@example.com
+    notes_password_env: ALICE_NOTES_PASSWORD
+  - memos_username: bob
+    notes_token_env: BOB_NOTES_TOKEN
+`,
+			want: []MappingEntry{
+				{MemosUsername: "alice", NotesEmail: "alice@example.com", NotesPasswordEnv: "ALICE_NOTES_PASSWORD"},
+				{MemosUsername: "bob", NotesTokenEnv: "BOB_NOTES_TOKEN"},
+			},
+		},
+		{
+			name: "comments, blank lines and a document start",
+			yaml: `---
+# who to migrate
+users:
+
+  # the admin
+  - memos_username: alice # inline
+    notes_password: pass#word
+`,
+			want: []MappingEntry{{MemosUsername: "alice", NotesPassword: "pass#word"}},
+		},
+		{
+			name: "item on its own line, unindented list",
+			yaml: "users:\n-\n  memos_username: alice\n  notes_token: t\n",
+			want: []MappingEntry{{MemosUsername: "alice", NotesToken: "t"}},
+		},
+		{
+			name: "single quotes",
+			yaml: `users:
+  - memos_username: 'o''brien'
+    notes_password: 'a # b: "c"'
+`,
+			want: []MappingEntry{{MemosUsername: "o'brien", NotesPassword: `a # b: "c"`}},
+		},
+		{
+			name: "double quotes",
+			yaml: `users:
+  - memos_username: "tab\there"
+    notes_password: "q\"uote \\ it's # not a comment"
+    notes_token: "\x41é\U0001F600\e\N\_\/"
+`,
+			want: []MappingEntry{{
+				MemosUsername: "tab\there",
+				NotesPassword: `q"uote \ it's # not a comment`,
+				NotesToken:    "Aé\U0001F600\x1b\u0085 /",
+			}},
+		},
+		{
+			name: "YAML escapes, not Go's",
+			// In Go, "\xe9" is one byte; in YAML it is the code point é.
+			yaml: "users:\n  - memos_username: \"caf\\xe9\"\n",
+			want: []MappingEntry{{MemosUsername: "café"}},
+		},
+		{
+			name: "nulls and apostrophes in plain scalars",
+			yaml: "users:\n  - memos_username: it's\n    notes_email: ~\n    notes_token: null\n    notes_token_env:\n",
+			want: []MappingEntry{{MemosUsername: "it's"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mf MappingFile
+			if err := parseYAMLMappings([]byte(tt.yaml), &mf); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(mf.Users, tt.want) {
+				t.Errorf("users = %+v, want %+v", mf.Users, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLMappingsRejects(t *testing.T) {
+	tests := []struct {
+		name, yaml, err string
+	}{
+		{"other top-level key", "people:\n  - memos_username: a\n", `line 1: expected top-level "users:"`},
+		{"flow list", "users: []\n", `line 1: expected top-level "users:"`},
+		{"users twice", "users:\n  - memos_username: a\nusers:\n", `line 3: "users" is given twice`},
+		{"indented before users", "  - memos_username: a\n", `line 1: expected top-level "users:" first`},
+		{"map instead of list", "users:\n  memos_username: a\n", `line 2: expected a list item`},
+		{"unknown key", "users:\n  - memos_user: a\n", `line 2: unknown key "memos_user"`},
+		{"repeated key", "users:\n  - memos_username: a\n    memos_username: b\n", `line 3: key "memos_username" is given twice`},
+		{"nested map", "users:\n  - memos_username: a\n    notes_email:\n      work: a@example.com\n", `line 4: keys of a list item must line up`},
+		{"nested list", "users:\n  - memos_username: a\n    - b\n", `line 3: list items must line up; nested lists are not supported`},
+		{"misaligned items", "users:\n  - memos_username: a\n    - memos_username: b\n", `line 3: list items must line up`},
+		{"tab indent", "users:\n\t- memos_username: a\n", `line 2: tabs are not allowed`},
+		{"no colon", "users:\n  - memos_username\n", `line 2: expected "key: value"`},
+		{"no space after colon", "users:\n  - memos_username:a\n", `line 2: expected "key: value"`},
+		{"flow map value", "users:\n  - memos_username: {a: b}\n", `line 2: memos_username: "{a: b}" is not a plain string`},
+		{"anchor", "users:\n  - memos_username: &a alice\n", `is not a plain string`},
+		{"block scalar", "users:\n  - notes_password: |\n", `is not a plain string`},
+		{"colon in plain value", "users:\n  - notes_password: a: b\n", `holds ": "`},
+		{"unterminated double", "users:\n  - notes_password: \"abc\n", `line 2: unterminated "-quoted string`},
+		{"unterminated single", "users:\n  - notes_password: 'abc\n", `line 2: unterminated '-quoted string`},
+		{"text after double", "users:\n  - notes_password: \"a\" b\n", `text after the closing quote`},
+		{"text after single", "users:\n  - notes_password: 'a' b\n", `text after the closing quote`},
+		{"unknown escape", `users:` + "\n" + `  - notes_password: "a\qb"` + "\n", `unknown escape \q`},
+		{"short hex escape", `users:` + "\n" + `  - notes_password: "\x4"` + "\n", `escape`},
+		{"bad unicode escape", `users:` + "\n" + `  - notes_password: "\uD800"` + "\n", `invalid \u escape`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mf MappingFile
+			err := parseYAMLMappings([]byte(tt.yaml), &mf)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadMappingFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	want := []MappingEntry{{MemosUsername: "alice", NotesToken: "t"}}
+
+	for _, path := range []string{
+		write("m.json", `{"users": [{"memos_username": "alice", "notes_token": "t"}]}`),
+		write("m.yml", "users:\n  - memos_username: alice\n    notes_token: t\n"),
+	} {
+		mf, err := readMappingFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !reflect.DeepEqual(mf.Users, want) {
+			t.Errorf("%s: users = %+v, want %+v", path, mf.Users, want)
+		}
+	}
+
+	for path, msg := range map[string]string{
+		write("unknown.json", `{"users": [{"memos_user": "alice"}]}`): "unknown field",
+		write("empty.yaml", "users:\n"):                               "lists no users",
+		write("m.toml", ""):                                           "must end in .json, .yaml or .yml",
+	} {
+		if _, err := readMappingFile(path); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: error = %v, want one containing %q", filepath.Base(path), err, msg)
+		}
+	}
+}
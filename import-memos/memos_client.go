@@ -2,12 +2,17 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/mbright/notesapi"
 )
 
 // MemosClient interacts with a Memos instance REST API.
@@ -199,9 +204,45 @@ func (c *MemosClient) ListAllMemos(creatorName string) ([]MemosMemo, error) {
 	return all, nil
 }
 
+// ListMemoComments returns the comments on a memo, oldest first.
+// memoName is e.g. "memos/abc123".
+func (c *MemosClient) ListMemoComments(memoName string) ([]MemosMemo, error) {
+	var comments []MemosMemo
+	pageToken := ""
+	for {
+		params := url.Values{}
+		params.Set("pageSize", "200")
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		path := "/api/v1/" + memoName + "/comments?" + params.Encode()
+		body, err := c.doRequest("GET", path)
+		if err != nil {
+			return nil, fmt.Errorf("listing comments on %s: %w", memoName, err)
+		}
+
+		var resp MemosListMemosResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("parsing comments response: %w", err)
+		}
+		comments = append(comments, resp.Memos...)
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	slices.SortStableFunc(comments, func(a, b MemosMemo) int {
+		return a.CreateTime.Compare(b.CreateTime)
+	})
+	return comments, nil
+}
+
 // DownloadAttachment downloads an attachment file from the Memos file server.
 // attachmentName is like "attachments/uid123", filename is the original filename.
-func (c *MemosClient) DownloadAttachment(attachmentName, filename string) (*FileData, error) {
+func (c *MemosClient) DownloadAttachment(attachmentName, filename string) (*notesapi.File, error) {
 	// Extract UID from "attachments/uid123"
 	parts := strings.SplitN(attachmentName, "/", 2)
 	if len(parts) != 2 {
@@ -237,9 +278,58 @@ func (c *MemosClient) DownloadAttachment(attachmentName, filename string) (*File
 		contentType = "application/octet-stream"
 	}
 
-	return &FileData{
+	return &notesapi.File{
 		Filename:    filename,
 		ContentType: contentType,
 		Data:        data,
 	}, nil
 }
+
+// DownloadExternalAttachment fetches an attachment that Memos only links to
+// from wherever it is hosted. The Memos token is not sent. Files too large
+// for Notes are refused without being read in full.
+func (c *MemosClient) DownloadExternalAttachment(link, filename string) (*notesapi.File, error) {
+	const maxBytes = notesapi.MaxAttachmentBytes
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("%w: %q", errUnsupportedLink, link)
+	}
+	resp, err := c.httpClient.Get(link)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", link, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("HTTP %d downloading %s", resp.StatusCode, link)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("%s: %w (%.1f MB)", link, errAttachmentTooLarge, float64(resp.ContentLength)/(1<<20))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", link, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("%s: %w", link, errAttachmentTooLarge)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if filename == "" {
+		filename = path.Base(u.Path)
+	}
+	return &notesapi.File{
+		Filename:    filename,
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+// Errors for external attachments that no retry can fetch.
+var (
+	errAttachmentTooLarge = errors.New("larger than the 25 MB attachment limit")
+	errUnsupportedLink    = errors.New("not an http or https link")
+)
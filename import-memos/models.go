@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -70,7 +71,33 @@ type MemosMemo struct {
 	Pinned      bool              `json:"pinned"`
 	Attachments []MemosAttachment `json:"attachments"`
 	Snippet     string            `json:"snippet"`
-	Parent      string            `json:"parent"`
+	Parent      string            `json:"parent"` // the memo a comment is on, e.g. "memos/abc123"
+	Relations   []MemosRelation   `json:"relations"`
+	Reactions   []MemosReaction   `json:"reactions"`
+	// Comments is the thread of comments on the memo, oldest first, when
+	// they are folded into its note. Replies are nested in each comment.
+	Comments []MemosMemo `json:"-"`
+}
+
+// MemosRelation links two memos: Memo references RelatedMemo, or is a
+// comment on it. A memo lists its relations in both directions.
+type MemosRelation struct {
+	Memo        MemosRelatedMemo `json:"memo"`
+	RelatedMemo MemosRelatedMemo `json:"relatedMemo"`
+	Type        string           `json:"type"` // REFERENCE, COMMENT
+}
+
+// MemosReaction is an emoji a user reacted to a memo with.
+type MemosReaction struct {
+	Creator      string `json:"creator"`      // e.g. "users/1"
+	ContentID    string `json:"contentId"`    // e.g. "memos/abc123"
+	ReactionType string `json:"reactionType"` // the emoji, e.g. "👍"
+}
+
+// MemosRelatedMemo identifies one end of a relation.
+type MemosRelatedMemo struct {
+	Name    string `json:"name"`
+	Snippet string `json:"snippet"`
 }
 
 // MemosListMemosResponse is the response from GET /api/v1/memos.
@@ -85,77 +112,71 @@ type MemosUserStats struct {
 	TagCount map[string]int `json:"tagCount"`
 }
 
-// --- Notes API models ---
-
-// NotesUser is not explicitly returned; we use auth info.
-// We track users by their token and what the auth endpoint returns.
-
-// NotesTag represents a tag from the Notes API.
-type NotesTag struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Color string `json:"color"`
-}
-
-// NotesTagsResponse is the response from GET /api/v1/tags.
-type NotesTagsResponse struct {
-	Tags []NotesTag `json:"tags,omitempty"`
-}
-
-// NotesNote represents a note from the Notes API.
-type NotesNote struct {
-	ID       int        `json:"id"`
-	Title    string     `json:"title"`
-	Body     string     `json:"body"`
-	Pinned   bool       `json:"pinned"`
-	Archived bool       `json:"archived"`
-	Trashed  bool       `json:"trashed"`
-	MaxSize  int        `json:"max_size"`
-	UserID   int        `json:"user_id"`
-	Tags     []NotesTag `json:"tags"`
-}
-
-// NotesPagination is the pagination info from Notes list endpoints.
-type NotesPagination struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
-	Pages int `json:"pages"`
-	Count int `json:"count"`
-}
-
-// NotesListResponse is the response from GET /api/v1/notes.
-type NotesListResponse struct {
-	Notes      []NotesNote     `json:"notes"`
-	Pagination NotesPagination `json:"pagination"`
-}
-
-// NotesAttachment represents attachment metadata from the Notes API.
-type NotesAttachment struct {
-	ID          int    `json:"id"`
-	Filename    string `json:"filename"`
-	ContentType string `json:"content_type"`
-	ByteSize    int64  `json:"byte_size"`
-}
-
-// FileData holds a downloaded file ready for upload.
-type FileData struct {
-	Filename    string
-	ContentType string
-	Data        []byte
-}
-
 // UserMapping holds the mapping from a Memos user to a Notes user.
 type UserMapping struct {
 	MemosUserName    string // e.g. "users/1"
 	MemosUsername     string
 	MemosDisplayName string
+	NotesEmail       string
+	NotesPassword    string // kept so an expired token can be re-issued
 	NotesToken       string
+	// NotesTokenExpiresAt is the token's expiry; the Notes client refreshes
+	// the token shortly before then and writes the new one back here.
+	NotesTokenExpiresAt time.Time
+}
+
+// notesAccount identifies the Notes account a mapping migrates into: its
+// email, or its token if it has none.
+func (m *UserMapping) notesAccount() string {
+	if m.NotesEmail != "" {
+		return strings.ToLower(m.NotesEmail)
+	}
+	return m.NotesToken
 }
 
 // MigrationStats tracks stats for a single user migration.
 type MigrationStats struct {
-	NotesCreated       int
-	TagsCreated        int
+	NotesCreated        int
+	NotesUpdated        int // changed since a previous run, rewritten with --update
+	NotesResumed        int // partially imported by a previous run, finished now
+	NotesJournaled      int // fully imported by a previous run, skipped
+	NotesSkipped        int // found in Notes without a journal entry, skipped
+	TagsCreated         int
 	AttachmentsUploaded int
-	Errors             []string
+	FilesLinked         int // links to memo attachments pointed at Notes
+	ExternalLinked      int // external attachments listed as links
+	ExternalFetched     int // external attachments downloaded and uploaded
+	CommentsFolded      int // comments written into their memo's note
+	ReactionsKept       int // reactions summarized in note footers
+	NotesLinked         int // notes whose references were linked in the second pass
+	SharesCreated       int
+	// UnmatchedShares counts, by email, the notes that could not be shared
+	// because the email has no Notes account.
+	UnmatchedShares map[string]int
+	Errors          []string
+}
+
+// add accumulates the counters and errors of o into s.
+func (s *MigrationStats) add(o *MigrationStats) {
+	s.NotesCreated += o.NotesCreated
+	s.NotesUpdated += o.NotesUpdated
+	s.NotesResumed += o.NotesResumed
+	s.NotesJournaled += o.NotesJournaled
+	s.NotesSkipped += o.NotesSkipped
+	s.TagsCreated += o.TagsCreated
+	s.AttachmentsUploaded += o.AttachmentsUploaded
+	s.FilesLinked += o.FilesLinked
+	s.ExternalLinked += o.ExternalLinked
+	s.ExternalFetched += o.ExternalFetched
+	s.CommentsFolded += o.CommentsFolded
+	s.ReactionsKept += o.ReactionsKept
+	s.NotesLinked += o.NotesLinked
+	s.SharesCreated += o.SharesCreated
+	for email, n := range o.UnmatchedShares {
+		if s.UnmatchedShares == nil {
+			s.UnmatchedShares = make(map[string]int)
+		}
+		s.UnmatchedShares[email] += n
+	}
+	s.Errors = append(s.Errors, o.Errors...)
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// reactionsSummary renders a memo's reactions as one line, each emoji with
+// its count, most used first, followed by who reacted, e.g.
+// "👍 3 · 🎉 1, from alice, bob". It returns "" if there are none.
+func reactionsSummary(reactions []MemosReaction, author func(string) string) string {
+	var emojis, people []string
+	counts := make(map[string]int)
+	for _, r := range reactions {
+		if r.ReactionType == "" {
+			continue
+		}
+		if counts[r.ReactionType] == 0 {
+			emojis = append(emojis, r.ReactionType)
+		}
+		counts[r.ReactionType]++
+		if name := author(r.Creator); !slices.Contains(people, name) {
+			people = append(people, name)
+		}
+	}
+	if len(emojis) == 0 {
+		return ""
+	}
+	// Stable, so that ties keep the order in which they were first used.
+	slices.SortStableFunc(emojis, func(a, b string) int { return counts[b] - counts[a] })
+
+	parts := make([]string, len(emojis))
+	for i, e := range emojis {
+		parts[i] = fmt.Sprintf("%s %d", e, counts[e])
+	}
+	return strings.Join(parts, " · ") + ", from " + strings.Join(people, ", ")
+}
+
+// reactionsFooter renders the reactions on a memo as the last line of its
+// note, before its References, or returns "" if there are none.
+func reactionsFooter(reactions []MemosReaction, author func(string) string) string {
+	summary := reactionsSummary(reactions, author)
+	if summary == "" {
+		return ""
+	}
+	return "*Reactions: " + summary + "*\n"
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/provenance"
+)
+
+// Ways of migrating comments, chosen with --comments.
+const (
+	// commentsInline folds each memo's comment thread into its note.
+	commentsInline = "inline"
+	// commentsNotes migrates each comment as a note of its own that links
+	// to the note of the memo it is on.
+	commentsNotes = "notes"
+	// commentsNone leaves comments out.
+	commentsNone = "none"
+)
+
+// referencesHeading starts the section that links a note to the notes of
+// the memos its memo references. It is written last, before any trailer.
+const referencesHeading = "## References\n\n"
+
+func checkCommentsMode(mode string) error {
+	switch mode {
+	case commentsInline, commentsNotes, commentsNone:
+		return nil
+	}
+	return fmt.Errorf("invalid --comments %q: want inline, notes or none", mode)
+}
+
+// hasComments reports whether anyone commented on a memo.
+func hasComments(memo MemosMemo) bool {
+	for _, r := range memo.Relations {
+		if r.Type == "COMMENT" && r.RelatedMemo.Name == memo.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// withComments fetches the comment threads of memos. Inline, each thread is
+// attached to its memo; as notes, the comments are appended to the list to
+// be migrated like memos. Comments listed among the memos, as some Memos
+// versions do, are dropped in favour of their thread. It also returns the
+// number of comments found.
+func withComments(c *MemosClient, memos []MemosMemo, mode string) ([]MemosMemo, int, error) {
+	if mode == commentsNone {
+		return memos, 0, nil
+	}
+	var out, comments []MemosMemo
+	total := 0
+	for _, memo := range memos {
+		if memo.Parent != "" {
+			continue
+		}
+		if hasComments(memo) {
+			thread, n, err := fetchThread(c, memo.Name)
+			if err != nil {
+				return nil, 0, err
+			}
+			total += n
+			if mode == commentsInline {
+				memo.Comments = thread
+			} else {
+				comments = append(comments, flattenThread(thread)...)
+			}
+		}
+		out = append(out, memo)
+	}
+	return append(out, comments...), total, nil
+}
+
+// fetchThread returns the comments on a memo with their replies nested, and
+// how many there are in all.
+func fetchThread(c *MemosClient, memoName string) ([]MemosMemo, int, error) {
+	comments, err := c.ListMemoComments(memoName)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := len(comments)
+	for i := range comments {
+		if !hasComments(comments[i]) {
+			continue
+		}
+		replies, n, err := fetchThread(c, comments[i].Name)
+		if err != nil {
+			return nil, 0, err
+		}
+		comments[i].Comments = replies
+		total += n
+	}
+	return comments, total, nil
+}
+
+// flattenThread lists a thread's comments and replies depth first.
+func flattenThread(thread []MemosMemo) []MemosMemo {
+	var out []MemosMemo
+	for _, c := range thread {
+		replies := c.Comments
+		c.Comments = nil
+		out = append(out, c)
+		out = append(out, flattenThread(replies)...)
+	}
+	return out
+}
+
+// countComments returns the number of comments in a thread, replies
+// included.
+func countComments(thread []MemosMemo) int {
+	n := len(thread)
+	for _, c := range thread {
+		n += countComments(c.Comments)
+	}
+	return n
+}
+
+// allAttachments returns a memo's attachments followed by those of its
+// folded comments, which are uploaded to the same note.
+func allAttachments(memo MemosMemo) []MemosAttachment {
+	atts := memo.Attachments
+	for _, c := range memo.Comments {
+		atts = append(atts, allAttachments(c)...)
+	}
+	return atts
+}
+
+// commentsSection renders a comment thread as a "Comments" section. Each
+// comment is a byline followed by its text as a block quote; replies are
+// nested in the quote of the comment they answer.
+func commentsSection(thread []MemosMemo, author func(string) string) string {
+	return "## Comments\n\n" + renderThread(thread, author)
+}
+
+func renderThread(thread []MemosMemo, author func(string) string) string {
+	var sb strings.Builder
+	for i, c := range thread {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(byline(c, author) + "\n\n")
+
+		text := strings.TrimSpace(c.Content)
+		if len(c.Attachments) > 0 {
+			names := make([]string, len(c.Attachments))
+			for j, a := range c.Attachments {
+				names[j] = a.Filename
+			}
+			text += "\n\n*Attached: " + strings.Join(names, ", ") + "*"
+		}
+		if len(c.Comments) > 0 {
+			text += "\n\n" + renderThread(c.Comments, author)
+		}
+		sb.WriteString(quote(text))
+	}
+	return sb.String()
+}
+
+// byline names a comment's author and when it was written.
+func byline(c MemosMemo, author func(string) string) string {
+	return fmt.Sprintf("**%s** · %s", author(c.Creator), c.CreateTime.UTC().Format("2006-01-02 15:04 UTC"))
+}
+
+// quote turns text into a Markdown block quote.
+func quote(text string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			sb.WriteString(">\n")
+		} else {
+			sb.WriteString("> " + line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// appendSection adds a section to the end of a note body.
+func appendSection(body, section string) string {
+	if strings.TrimSpace(body) == "" {
+		return section
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + section
+}
+
+// withoutReferences returns body without its References section, whose
+// links depend on the IDs of other notes.
+func withoutReferences(body string) string {
+	if i := strings.LastIndex(body, "\n\n"+referencesHeading); i >= 0 {
+		return body[:i]
+	}
+	if strings.HasPrefix(body, referencesHeading) {
+		return ""
+	}
+	return body
+}
+
+// memoRef is a memo that a memo's note links to.
+type memoRef struct {
+	name    string
+	snippet string
+	// reply marks the memo that a comment is on.
+	reply bool
+}
+
+// outgoingRefs returns the memos a memo references and, for a comment, the
+// memo it is on, in the order Memos lists them.
+func outgoingRefs(memo MemosMemo) []memoRef {
+	var refs []memoRef
+	seen := make(map[string]bool)
+	for _, r := range memo.Relations {
+		if r.Memo.Name != memo.Name || seen[r.RelatedMemo.Name] {
+			continue
+		}
+		switch r.Type {
+		case "REFERENCE":
+			refs = append(refs, memoRef{r.RelatedMemo.Name, r.RelatedMemo.Snippet, false})
+		case "COMMENT":
+			refs = append(refs, memoRef{r.RelatedMemo.Name, r.RelatedMemo.Snippet, true})
+		default:
+			continue
+		}
+		seen[r.RelatedMemo.Name] = true
+	}
+	return refs
+}
+
+// linkOwner is the Notes account that a Memos user's memos are migrated
+// into.
+type linkOwner struct {
+	client *notesapi.Client
+	// account identifies the Notes account; links only work between notes
+	// of one account.
+	account string
+	user    string // Memos username, for the summary
+}
+
+// linkedNote is the note a memo became.
+type linkedNote struct {
+	account string
+	id      int
+	title   string
+}
+
+// linkJob is a note whose links could not all be resolved when it was
+// written.
+type linkJob struct {
+	linkOwner
+	memo MemosMemo
+}
+
+// memoLinks records which note each memo became, so that references between
+// memos can be written as links between notes. Notes whose links could not
+// all be resolved when they were written are kept for a second pass, once
+// every memo has been migrated. It is safe for concurrent use.
+type memoLinks struct {
+	mu      sync.Mutex
+	notes   map[string]linkedNote // memo name → note
+	pending []linkJob
+}
+
+func newMemoLinks() *memoLinks {
+	return &memoLinks{notes: make(map[string]linkedNote)}
+}
+
+// add records the note a memo became in a Notes account.
+func (l *memoLinks) add(memoName, account string, id int, title string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.notes[memoName] = linkedNote{account, id, title}
+}
+
+// resolve renders the References section of a memo's note, linking the
+// notes in the account that the memos it refers to became. It also returns
+// the links made, and whether every reference was linked. A memo that refers
+// to nothing has no section.
+func (l *memoLinks) resolve(memo MemosMemo, account string) (section string, links map[string]int, complete bool) {
+	refs := outgoingRefs(memo)
+	if len(refs) == 0 {
+		return "", nil, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString(referencesHeading)
+	complete = true
+	for _, ref := range refs {
+		label := linkLabel(ref.snippet, ref.name)
+		prefix := "- "
+		if ref.reply {
+			prefix = "- In reply to "
+		}
+		note, ok := l.notes[ref.name]
+		if !ok || note.account != account {
+			fmt.Fprintf(&sb, "%s%s (%s in Memos, not imported here)\n", prefix, label, ref.name)
+			complete = false
+			continue
+		}
+		if note.title != "" {
+			label = linkLabel(note.title, ref.name)
+		}
+		fmt.Fprintf(&sb, "%s[%s](/notes/%d)\n", prefix, label, note.id)
+		if links == nil {
+			links = make(map[string]int)
+		}
+		links[ref.name] = note.id
+	}
+	return sb.String(), links, complete
+}
+
+// linkLabel returns text fit for a link: one line of at most 60 characters,
+// with brackets escaped, or fallback if text is empty.
+func linkLabel(text, fallback string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return fallback
+	}
+	if r := []rune(text); len(r) > 60 {
+		text = string(r[:60]) + "…"
+	}
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+}
+
+// retry keeps a note for the second pass.
+func (l *memoLinks) retry(job linkJob) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, job)
+}
+
+// linkReferences is the second pass: it rewrites the References section of
+// every note whose links were incomplete, now that every memo has a note,
+// wherever it links to more notes than recorded in the journal. The rest of
+// each note is left as it is in Notes.
+func linkReferences(opts *migrateOptions, allStats map[string]*MigrationStats) {
+	pending := opts.links.pending
+	if len(pending) == 0 {
+		return
+	}
+	fmt.Printf("\nSecond pass: resolving the references of %d note(s)...\n", len(pending))
+	for _, job := range pending {
+		stats := allStats[job.user]
+		linked, err := relinkNote(job, opts)
+		if err != nil {
+			msg := fmt.Sprintf("linking references of memo %s: %v", job.memo.Name, err)
+			fmt.Printf("  [%s] Error: %s\n", job.user, msg)
+			stats.Errors = append(stats.Errors, msg)
+		} else if linked {
+			stats.NotesLinked++
+		}
+	}
+}
+
+// relinkNote rewrites the References section of one note if it can link
+// more notes than before, keeping the memo's update time, and reports
+// whether it did.
+func relinkNote(job linkJob, opts *migrateOptions) (bool, error) {
+	entry := opts.journal.Get(job.memo.Name)
+	if entry == nil {
+		return false, nil
+	}
+	section, links, _ := opts.links.resolve(job.memo, job.account)
+	if maps.Equal(links, entry.Links) {
+		return false, nil
+	}
+
+	note, err := job.client.GetNote(entry.NoteID)
+	if err != nil {
+		return false, err
+	}
+	body := provenance.Edit(note.Body, func(s string) string {
+		return appendSection(withoutReferences(s), section)
+	})
+	if opts.apiDelay > 0 {
+		time.Sleep(opts.apiDelay)
+	}
+	// Keep the memo's update time, which the server would otherwise replace
+	// with now.
+	params := notesapi.NoteParams{Body: notesapi.String(body), UpdatedAt: job.memo.UpdateTime}
+	if len(body) > 32768 {
+		params.MaxSize = len(body) + 1024
+	}
+	if _, err := job.client.UpdateNote(entry.NoteID, params); err != nil {
+		return false, err
+	}
+	fmt.Printf("  [%s] Linked note #%d to %d note(s)\n", job.user, entry.NoteID, len(links))
+
+	entry.Links = links
+	return true, opts.journal.Record(job.memo.Name, entry)
+}
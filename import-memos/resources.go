@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/mbright/notesapi"
+)
+
+// resourceLink matches a link to a file served by Memos, relative or on any
+// host, e.g. "/file/attachments/uid123/photo.png?thumbnail=true". The
+// attachment's UID is captured.
+var resourceLink = regexp.MustCompile(`(?:https?://[^/\s()<>"']+)?/file/attachments/([^/\s()<>"'?#]+)/[^\s()<>"'?#]*(?:\?[^\s()<>"'#]*)?`)
+
+// notesAttachmentLink matches a link written by rewriteResourceLinks.
+var notesAttachmentLink = regexp.MustCompile(`/notes/\d+/attachments/\d+`)
+
+// attachmentPath returns the URL path at which Notes serves an attachment.
+func attachmentPath(noteID, attachmentID int) string {
+	return fmt.Sprintf("/notes/%d/attachments/%d", noteID, attachmentID)
+}
+
+// linkedAttachments returns the names of the attachments, among atts, that
+// body links to, e.g. "attachments/uid123".
+func linkedAttachments(body string, atts []MemosAttachment) []string {
+	var names []string
+	for _, m := range resourceLink.FindAllStringSubmatch(body, -1) {
+		name := "attachments/" + m[1]
+		if slices.ContainsFunc(atts, func(a MemosAttachment) bool { return a.Name == name }) && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// rewriteResourceLinks points the links in body to Memos files at the Notes
+// attachments they were uploaded as, given by ids (attachment name → Notes
+// attachment ID). It also returns the number of links rewritten, and whether
+// every link to one of the memo's attachments was. Links to files of other
+// memos are left alone.
+func rewriteResourceLinks(body string, noteID int, ids map[string]int, atts []MemosAttachment) (string, int, bool) {
+	n := 0
+	complete := true
+	body = resourceLink.ReplaceAllStringFunc(body, func(link string) string {
+		name := "attachments/" + resourceLink.FindStringSubmatch(link)[1]
+		if id, ok := ids[name]; ok {
+			n++
+			return attachmentPath(noteID, id)
+		}
+		if slices.ContainsFunc(atts, func(a MemosAttachment) bool { return a.Name == name }) {
+			complete = false
+		}
+		return link
+	})
+	return body, n, complete
+}
+
+// resolveFileLinks rewrites the links in a note body to the memo's files.
+// If it links to an uploaded attachment that the journal entry has no Notes
+// ID for, the IDs of every such attachment are looked up first and added to
+// the entry.
+func resolveFileLinks(c *notesapi.Client, entry *JournalEntry, body string, atts []MemosAttachment) (string, int, bool, error) {
+	unknown := func(name string) bool {
+		_, ok := entry.AttachmentIDs[name]
+		return entry.Attachments[name] && !ok
+	}
+	if slices.ContainsFunc(linkedAttachments(body, atts), unknown) {
+		var unmatched []MemosAttachment
+		for _, a := range atts {
+			if unknown(a.Name) {
+				unmatched = append(unmatched, a)
+			}
+		}
+		have, err := c.ListAttachments(entry.NoteID)
+		if err != nil {
+			return "", 0, false, err
+		}
+		if entry.AttachmentIDs == nil {
+			entry.AttachmentIDs = make(map[string]int)
+		}
+		matchAttachmentIDs(have, unmatched, entry.AttachmentIDs)
+	}
+	body, n, complete := rewriteResourceLinks(body, entry.NoteID, entry.AttachmentIDs, atts)
+	return body, n, complete, nil
+}
+
+// withoutFileLinks returns body with its links to files, in Memos or in
+// Notes, removed, so that a note whose links were rewritten still matches
+// its memo.
+func withoutFileLinks(body string) string {
+	return notesAttachmentLink.ReplaceAllString(resourceLink.ReplaceAllString(body, ""), "")
+}
+
+// matchAttachmentIDs finds the Notes attachments that files uploaded to a
+// note became, by filename, and records their IDs in ids. Attachment IDs
+// grow with each upload, so when a filename occurs more than once, the
+// last files uploaded are matched to the highest IDs not yet taken.
+func matchAttachmentIDs(have []notesapi.Attachment, uploaded []MemosAttachment, ids map[string]int) {
+	taken := make(map[int]bool)
+	for _, id := range ids {
+		taken[id] = true
+	}
+	for i := len(uploaded) - 1; i >= 0; i-- {
+		best := 0
+		for _, a := range have {
+			if a.Filename == uploaded[i].Filename && !taken[a.ID] && a.ID > best {
+				best = a.ID
+			}
+		}
+		if best != 0 {
+			ids[uploaded[i].Name] = best
+			taken[best] = true
+		}
+	}
+}
+
+// linkFiles rewrites the links to the memo's files in a note's body, which
+// is written over the note, keeping updatedAt, if any link changed. It
+// returns the number of links rewritten, and marks the entry FilesLinked
+// once every link is.
+func linkFiles(c *notesapi.Client, entry *JournalEntry, body string, atts []MemosAttachment, maxSize int, updatedAt time.Time, apiDelay time.Duration) (int, error) {
+	body, n, complete, err := resolveFileLinks(c, entry, body, atts)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		if apiDelay > 0 {
+			time.Sleep(apiDelay)
+		}
+		_, err := c.UpdateNote(entry.NoteID, notesapi.NoteParams{
+			Body:      notesapi.String(body),
+			MaxSize:   maxSize,
+			UpdatedAt: updatedAt,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	entry.FilesLinked = complete
+	return n, nil
+}
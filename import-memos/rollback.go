@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mbright/notesapi"
+	"github.com/mbright/notesapi/credentials"
+	"github.com/mbright/notesapi/manifest"
+)
+
+// rollback implements the rollback command, which undoes a migration run
+// recorded in a manifest, account by account: notes it created are deleted
+// for good, tags it created are deleted if unused, and attachments it added
+// to existing notes are removed.
+func rollback(args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: import-memos rollback [flags] <manifest>\n")
+		fs.PrintDefaults()
+	}
+	notesURL := fs.String("notes-url", "", "Base URL of the Notes instance (default: the one the manifest was written for)")
+	notesToken := fs.String("notes-token", "", "Notes API token to roll back every account with (overrides NOTES_TOKEN)")
+	mappingPath := fs.String("mapping", "", "JSON or YAML mapping file to take each account's credentials from (skips the prompts)")
+	yes := fs.Bool("yes", false, "Delete without asking for confirmation")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	m, err := manifest.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	switch {
+	case *notesURL == "":
+		*notesURL = m.NotesURL
+	case m.NotesURL != "" && strings.TrimRight(*notesURL, "/") != strings.TrimRight(m.NotesURL, "/"):
+		return fmt.Errorf("the manifest is for %s, not %s", m.NotesURL, *notesURL)
+	}
+	if *notesURL == "" {
+		return fmt.Errorf("--notes-url is required")
+	}
+
+	var mf *MappingFile
+	if *mappingPath != "" {
+		if mf, err = readMappingFile(*mappingPath); err != nil {
+			return err
+		}
+	}
+	shared := sharedCredentials(*notesToken)
+	scanner := bufio.NewScanner(os.Stdin)
+	limiter := notesapi.NewTokenBucket(notesapi.DefaultRateLimit, notesapi.DefaultRateWindow)
+
+	failed := 0
+	for _, a := range m.Accounts {
+		if a.Empty() {
+			continue
+		}
+		fmt.Printf("\n[%s] %s to roll back\n", a, a.Summary())
+
+		creds, err := rollbackCredentials(a, shared, mf, scanner, *notesURL)
+		if err != nil {
+			return fmt.Errorf("%s: %w", a, err)
+		}
+		if creds.Email != "" && a.Email != "" && !strings.EqualFold(creds.Email, a.Email) {
+			return fmt.Errorf("%s: the notes were imported into %s, but these credentials are for %s", a, a.Email, creds.Email)
+		}
+		c := notesapi.NewClient(*notesURL, "")
+		c.Limiter = limiter
+		if err := credentials.Login(c, *creds); err != nil {
+			return fmt.Errorf("authenticating %s: %w", creds, err)
+		}
+
+		if !*yes {
+			if !credentials.IsTerminal(os.Stdin) {
+				return fmt.Errorf("refusing to delete without confirmation; pass --yes")
+			}
+			fmt.Printf("Permanently delete %s that the %s run created for %s? [y/N] ",
+				a.Summary(), m.StartedAt.Local().Format("2006-01-02 15:04"), a)
+			if !scanner.Scan() {
+				return fmt.Errorf("no input received")
+			}
+			if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+				fmt.Println("  Skipped")
+				continue
+			}
+		}
+
+		r := manifest.Rollback(c, a, func(format string, args ...any) {
+			fmt.Printf("  "+format+"\n", args...)
+		})
+		fmt.Printf("[%s] %d note(s) deleted, %d attachment(s) removed, %d tag(s) deleted\n",
+			a, r.NotesDeleted, r.AttachmentsDeleted, r.TagsDeleted)
+		if len(r.NotUndone) > 0 {
+			fmt.Printf("  Not undone:\n")
+			for _, s := range r.NotUndone {
+				fmt.Printf("    - %s\n", s)
+			}
+		}
+		if len(r.Failed) > 0 {
+			fmt.Printf("  Failed:\n")
+			for _, s := range r.Failed {
+				fmt.Printf("    - %s\n", s)
+			}
+			failed += len(r.Failed)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d deletion(s) failed; run the rollback again to retry them", failed)
+	}
+	return nil
+}
+
+// rollbackCredentials returns the credentials for an account in a manifest:
+// the shared ones if given, else those of its entry in the mapping file, else
+// prompted for.
+func rollbackCredentials(a *manifest.Account, shared *credentials.Credentials, mf *MappingFile, scanner *bufio.Scanner, notesURL string) (*credentials.Credentials, error) {
+	if shared != nil {
+		return shared, nil
+	}
+	if mf != nil {
+		for _, e := range mf.Users {
+			if e.MemosUsername != a.Name {
+				continue
+			}
+			password, token, err := e.resolveCredentials(notesURL)
+			if err != nil {
+				return nil, err
+			}
+			return &credentials.Credentials{Email: e.NotesEmail, Password: password, Token: token, Source: "--mapping"}, nil
+		}
+		return nil, fmt.Errorf("no entry for %s in the mapping file", a.Name)
+	}
+	creds, err := promptCredentials(scanner, notesURL)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		return nil, fmt.Errorf("no Notes credentials given")
+	}
+	return creds, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Visibility policies, chosen with --share-visibility: the least visible
+// memos that are shared.
+const (
+	// shareProtected shares PROTECTED and PUBLIC memos.
+	shareProtected = "protected"
+	// sharePublic shares PUBLIC memos only.
+	sharePublic = "public"
+)
+
+// shareMapped in --share-with stands for the Notes account of every mapped
+// user.
+const shareMapped = "mapped"
+
+// visibilityTag returns the tag of notes made from memos of a visibility,
+// e.g. "visibility/public".
+func visibilityTag(visibility string) string {
+	return "visibility/" + strings.ToLower(visibility)
+}
+
+func checkShareVisibility(policy string) error {
+	switch policy {
+	case shareProtected, sharePublic:
+		return nil
+	}
+	return fmt.Errorf("invalid --share-visibility %q: want protected or public", policy)
+}
+
+// sharedVisibilities returns the visibilities that a policy shares.
+func sharedVisibilities(policy string) []string {
+	if policy == sharePublic {
+		return []string{"PUBLIC"}
+	}
+	return []string{"PROTECTED", "PUBLIC"}
+}
+
+// parseShareWith resolves a --share-with list of emails, in which "mapped"
+// stands for the Notes email of every mapped user. Users mapped by token
+// have no known email and are left out with a warning.
+func parseShareWith(spec string, mappings []UserMapping) ([]string, error) {
+	var emails []string
+	add := func(email string) {
+		email = strings.ToLower(email)
+		if !slices.Contains(emails, email) {
+			emails = append(emails, email)
+		}
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case item == shareMapped:
+			for _, m := range mappings {
+				if m.NotesEmail == "" {
+					fmt.Printf("Warning: %s is mapped with an API token, so its Notes email is unknown and it is not shared with\n", m.MemosUsername)
+					continue
+				}
+				add(m.NotesEmail)
+			}
+		case strings.Contains(item, "@"):
+			add(item)
+		default:
+			return nil, fmt.Errorf("invalid --share-with entry %q: want an email or %q", item, shareMapped)
+		}
+	}
+	return emails, nil
+}
+
+// isShared reports whether the policy shares a memo.
+func (o *migrateOptions) isShared(memo MemosMemo) bool {
+	return len(o.shareWith) > 0 && slices.Contains(sharedVisibilities(o.shareVisibility), memo.Visibility)
+}
+
+// recipients returns the emails a memo's note is shared with: none unless
+// the policy shares the memo, and never the account that owns the note.
+func (o *migrateOptions) recipients(memo MemosMemo, owner string) []string {
+	if !o.isShared(memo) {
+		return nil
+	}
+	var out []string
+	for _, email := range o.shareWith {
+		if email != owner {
+			out = append(out, email)
+		}
+	}
+	return out
+}
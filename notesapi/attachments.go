@@ -0,0 +1,36 @@
+package notesapi
+
+import "fmt"
+
+// MaxAttachmentBytes is the server's per-file upload limit.
+const MaxAttachmentBytes int64 = 25 * 1024 * 1024
+
+// ListAttachments returns the metadata of a note's attachments.
+func (c *Client) ListAttachments(noteID int) ([]Attachment, error) {
+	var atts []Attachment
+	if err := c.doJSON("GET", notePath(noteID)+"/attachments", nil, &atts); err != nil {
+		return nil, fmt.Errorf("listing attachments of note %d: %w", noteID, err)
+	}
+	return atts, nil
+}
+
+// UploadAttachments attaches files to a note in a single multipart request.
+// Files over MaxAttachmentBytes are rejected by the server with ErrValidation.
+func (c *Client) UploadAttachments(noteID int, files []File) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if err := c.doMultipart(notePath(noteID)+"/attachments", files); err != nil {
+		return fmt.Errorf("uploading attachments to note %d: %w", noteID, err)
+	}
+	return nil
+}
+
+// DeleteAttachment removes an attachment from a note.
+func (c *Client) DeleteAttachment(noteID, attachmentID int) error {
+	path := fmt.Sprintf("%s/attachments/%d", notePath(noteID), attachmentID)
+	if err := c.doJSON("DELETE", path, nil, nil); err != nil {
+		return fmt.Errorf("removing attachment %d from note %d: %w", attachmentID, noteID, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package notesapi
+
+import "fmt"
+
+// Authenticate exchanges an email and password for an API token and stores
+// it, its expiry and the credentials on the client for subsequent requests.
+func (c *Client) Authenticate(email, password string) (*Token, error) {
+	var tok Token
+	payload := map[string]string{"email": email, "password": password}
+	if err := c.doJSON("POST", "/api/v1/auth/token", payload, &tok); err != nil {
+		return nil, fmt.Errorf("authenticating with Notes: %w", err)
+	}
+	if tok.Token == "" {
+		return nil, fmt.Errorf("no token returned from Notes auth endpoint")
+	}
+	c.mu.Lock()
+	c.token, c.expiresAt = tok.Token, tok.ExpiresAt
+	c.email, c.password = email, password
+	c.mu.Unlock()
+	return &tok, nil
+}
+
+// Refresh extends the current token's lifetime (or issues a new token if it
+// has already expired) and stores the result on the client.
+func (c *Client) Refresh() (*Token, error) {
+	var tok Token
+	if err := c.doJSON("POST", "/api/v1/auth/refresh", nil, &tok); err != nil {
+		return nil, fmt.Errorf("refreshing Notes token: %w", err)
+	}
+	if tok.Token == "" {
+		return nil, fmt.Errorf("no token returned from Notes refresh endpoint")
+	}
+	c.SetToken(tok.Token, tok.ExpiresAt)
+	return &tok, nil
+}
+
+// Ping verifies connectivity and the token by listing tags, a lightweight
+// authenticated endpoint.
+func (c *Client) Ping() error {
+	_, err := c.ListTags()
+	return err
+}
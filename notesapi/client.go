@@ -0,0 +1,341 @@
+// Package notesapi is a client for the Notes REST API (/api/v1).
+//
+// It covers every route in the api/v1 namespace of web/config/routes.rb:
+// token auth, notes (including search, trash, merge, duplicate and export),
+// tags, shares, versions and attachments. Non-2xx responses are returned as
+// *APIError values that match ErrUnauthorized, ErrForbidden, ErrNotFound,
+// ErrValidation and ErrRateLimited with errors.Is.
+package notesapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 5
+	initialBackoff    = 2 * time.Second
+	// refreshMargin is how long before expiry a token is proactively refreshed.
+	refreshMargin = 10 * time.Minute
+)
+
+// Limiter throttles outgoing requests. Wait is called once before every
+// HTTP request, including retries.
+type Limiter interface {
+	Wait()
+}
+
+// pauser is implemented by limiters (such as TokenBucket) that can hold off
+// every caller when the server asks the client to back off.
+type pauser interface {
+	Pause(d time.Duration)
+}
+
+// Client talks to a single Notes instance on behalf of one user. It is safe
+// for concurrent use.
+//
+// When the token's expiry is known (after Authenticate, Refresh or SetToken
+// with a non-zero expiry) the client refreshes it shortly before it lapses.
+// A request rejected with HTTP 401 is retried once after refreshing the
+// token, falling back to the credentials given to Authenticate or
+// SetCredentials.
+type Client struct {
+	baseURL string
+
+	mu        sync.Mutex // guards token, expiresAt and credentials
+	token     string
+	expiresAt time.Time
+	email     string
+	password  string
+
+	// refreshMu serializes automatic refreshes so concurrent requests that
+	// hit an expired token only refresh it once.
+	refreshMu sync.Mutex
+
+	// HTTPClient is used for all requests. NewClient sets a 30s timeout.
+	HTTPClient *http.Client
+	// Limiter, if set, is waited on before each request.
+	Limiter Limiter
+	// MaxRetries is how many times a request rejected with HTTP 429 is retried.
+	MaxRetries int
+	// Logf, if set, receives progress messages such as rate-limit waits.
+	Logf func(format string, args ...any)
+	// OnTokenRefresh, if set, is called with the new token whenever the
+	// client refreshes or re-authenticates on its own.
+	OnTokenRefresh func(Token)
+}
+
+// NewClient creates a client for the Notes instance at baseURL
+// (e.g. http://localhost:3000). token may be empty until Authenticate is
+// called.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		HTTPClient: &http.Client{Timeout: defaultTimeout},
+		MaxRetries: defaultMaxRetries,
+	}
+}
+
+// BaseURL returns the instance URL the client was created with.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Token returns the bearer token currently used for requests.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// TokenExpiresAt returns the expiry of the current token, or the zero time if
+// it is unknown.
+func (c *Client) TokenExpiresAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.expiresAt
+}
+
+// SetToken replaces the bearer token used for requests. expiresAt may be
+// zero if unknown, which disables proactive refresh.
+func (c *Client) SetToken(token string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.expiresAt = expiresAt
+}
+
+// SetCredentials stores an email and password used to obtain a new token if
+// the current one can no longer be refreshed.
+func (c *Client) SetCredentials(email, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.email = email
+	c.password = password
+}
+
+func (c *Client) credentials() (email, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.email, c.password
+}
+
+// isAuthPath reports whether path is one of the token endpoints, which must
+// never trigger an automatic refresh themselves.
+func isAuthPath(path string) bool {
+	return strings.HasPrefix(path, "/api/v1/auth/")
+}
+
+// refreshIfExpiring proactively refreshes a token that is about to expire.
+func (c *Client) refreshIfExpiring() {
+	if exp := c.TokenExpiresAt(); exp.IsZero() || time.Until(exp) > refreshMargin {
+		return
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	// Another request may have refreshed while we waited for the lock.
+	if exp := c.TokenExpiresAt(); time.Until(exp) > refreshMargin {
+		return
+	}
+	if err := c.renewToken(); err != nil {
+		c.logf("Token refresh failed: %v", err)
+		// Stop retrying before every request; a 401 still triggers renewal.
+		c.mu.Lock()
+		c.expiresAt = time.Time{}
+		c.mu.Unlock()
+	}
+}
+
+// reauthenticate obtains a new token after stale was rejected with HTTP 401.
+func (c *Client) reauthenticate(stale string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	// Another request may have replaced the token while we waited.
+	if c.Token() != stale {
+		return nil
+	}
+	return c.renewToken()
+}
+
+// renewToken refreshes the token, falling back to a fresh login with the
+// stored credentials. The caller must hold refreshMu.
+func (c *Client) renewToken() error {
+	tok, err := c.Refresh()
+	if err != nil {
+		email, password := c.credentials()
+		if email == "" {
+			return err
+		}
+		c.logf("Token refresh failed (%v); re-authenticating as %s", err, email)
+		tok, err = c.Authenticate(email, password)
+		if err != nil {
+			return err
+		}
+	}
+	c.logf("Token refreshed (expires %s)", tok.ExpiresAt.Format(time.RFC3339))
+	if c.OnTokenRefresh != nil {
+		c.OnTokenRefresh(*tok)
+	}
+	return nil
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// send performs one HTTP request against an /api/v1 path with the given
+// bearer token and returns the status, headers and body.
+func (c *Client) send(method, path string, body []byte, contentType, token string) (int, http.Header, []byte, error) {
+	if c.Limiter != nil {
+		c.Limiter.Wait()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("creating request for %s: %w", path, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("requesting %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, resp.Header, nil, fmt.Errorf("reading response from %s %s: %w", method, path, err)
+	}
+	return resp.StatusCode, resp.Header, respBody, nil
+}
+
+// sendWithRetry performs a request, retrying HTTP 429 responses with
+// exponential backoff (or the server's Retry-After when present) and an
+// HTTP 401 response once after renewing the token. Any other non-2xx status
+// is returned as an *APIError.
+func (c *Client) sendWithRetry(method, path string, body []byte, contentType string) ([]byte, error) {
+	backoff := initialBackoff
+	retries := 0
+	reauthed := isAuthPath(path)
+	for {
+		if !isAuthPath(path) {
+			c.refreshIfExpiring()
+		}
+		token := c.Token()
+		status, header, respBody, err := c.send(method, path, body, contentType, token)
+		if err != nil {
+			return nil, err
+		}
+		if status >= 200 && status < 300 {
+			return respBody, nil
+		}
+
+		apiErr := newAPIError(method, path, status, header, respBody)
+		if status == http.StatusUnauthorized && !reauthed {
+			reauthed = true
+			if err := c.reauthenticate(token); err != nil {
+				c.logf("Re-authentication failed: %v", err)
+				return nil, apiErr
+			}
+			continue
+		}
+		if status != http.StatusTooManyRequests || retries >= c.MaxRetries {
+			return nil, apiErr
+		}
+
+		retries++
+		wait := backoff
+		if apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		c.logf("Rate limited on %s %s — waiting %v before retry %d/%d", method, path, wait, retries, c.MaxRetries)
+		if p, ok := c.Limiter.(pauser); ok {
+			// The limiter's next Wait blocks every goroutine sharing it.
+			p.Pause(wait)
+		} else {
+			time.Sleep(wait)
+		}
+		backoff *= 2
+	}
+}
+
+// doJSON sends payload (if non-nil) as JSON and decodes the response into
+// out (if non-nil).
+func (c *Client) doJSON(method, path string, payload, out any) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("marshaling JSON for %s: %w", path, err)
+		}
+	}
+
+	respBody, err := c.sendWithRetry(method, path, body, "application/json")
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing response from %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// quoteEscaper escapes a filename for a Content-Disposition header, as
+// mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// doMultipart uploads files as the files[] field of a multipart form.
+func (c *Client) doMultipart(path string, files []File) error {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[]"; filename="%s"`, quoteEscaper.Replace(f.Filename)))
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		h.Set("Content-Type", ct)
+
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return fmt.Errorf("creating form file for %s: %w", f.Filename, err)
+		}
+		if _, err := part.Write(f.Data); err != nil {
+			return fmt.Errorf("writing file data for %s: %w", f.Filename, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("closing multipart writer: %w", err)
+	}
+
+	_, err := c.sendWithRetry("POST", path, buf.Bytes(), w.FormDataContentType())
+	return err
+}
@@ -0,0 +1,190 @@
+package notesapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAuth is a Notes server that accepts one valid token, issues a new one
+// on refresh or login, and serves GET /api/v1/tags.
+type fakeAuth struct {
+	mu      sync.Mutex
+	valid   string
+	refresh bool // whether auth/refresh succeeds
+	calls   []string
+}
+
+func (f *fakeAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	token := r.Header.Get("Authorization")
+	issue := func(tok string) {
+		f.valid = tok
+		json.NewEncoder(w).Encode(Token{Token: tok, ExpiresAt: time.Now().Add(time.Hour)})
+	}
+	switch r.URL.Path {
+	case "/api/v1/auth/refresh":
+		if !f.refresh || token == "" {
+			http.Error(w, `{"error":"Invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		issue("refreshed")
+	case "/api/v1/auth/token":
+		var creds map[string]string
+		json.NewDecoder(r.Body).Decode(&creds)
+		if creds["password"] != "secret" {
+			http.Error(w, `{"error":"Invalid email or password"}`, http.StatusUnauthorized)
+			return
+		}
+		issue("login")
+	case "/api/v1/tags":
+		if token != "Bearer "+f.valid {
+			http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[{"id":1,"name":"work"}]`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeAuth) requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+func TestReauthenticate(t *testing.T) {
+	tests := []struct {
+		name        string
+		refresh     bool
+		password    string
+		wantToken   string
+		wantErr     error
+		wantCalls   []string
+		wantRenewed bool
+	}{
+		{
+			name: "refresh", refresh: true, wantToken: "refreshed", wantRenewed: true,
+			wantCalls: []string{"GET /api/v1/tags", "POST /api/v1/auth/refresh", "GET /api/v1/tags"},
+		},
+		{
+			name: "login after refresh fails", password: "secret", wantToken: "login", wantRenewed: true,
+			wantCalls: []string{"GET /api/v1/tags", "POST /api/v1/auth/refresh", "POST /api/v1/auth/token", "GET /api/v1/tags"},
+		},
+		{
+			name: "no credentials", wantToken: "stale", wantErr: ErrUnauthorized,
+			wantCalls: []string{"GET /api/v1/tags", "POST /api/v1/auth/refresh"},
+		},
+		{
+			name: "wrong password", password: "wrong", wantToken: "stale", wantErr: ErrUnauthorized,
+			wantCalls: []string{"GET /api/v1/tags", "POST /api/v1/auth/refresh", "POST /api/v1/auth/token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeAuth{valid: "current", refresh: tt.refresh}
+			srv := httptest.NewServer(f)
+			defer srv.Close()
+
+			c := NewClient(srv.URL, "stale")
+			if tt.password != "" {
+				c.SetCredentials("me@example.com", tt.password)
+			}
+			var renewed []Token
+			c.OnTokenRefresh = func(tok Token) { renewed = append(renewed, tok) }
+
+			tags, err := c.ListTags()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ListTags error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || len(tags) != 1 {
+				t.Fatalf("ListTags = %v, %v", tags, err)
+			}
+			if got := c.Token(); got != tt.wantToken {
+				t.Errorf("token = %q, want %q", got, tt.wantToken)
+			}
+			if (len(renewed) == 1) != tt.wantRenewed || len(renewed) > 1 {
+				t.Errorf("OnTokenRefresh called %d times", len(renewed))
+			}
+			if got := f.requests(); !slices.Equal(got, tt.wantCalls) {
+				t.Errorf("requests = %q, want %q", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRefreshBeforeExpiry(t *testing.T) {
+	f := &fakeAuth{valid: "current", refresh: true}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "")
+	c.SetToken("current", time.Now().Add(refreshMargin/2))
+	if _, err := c.ListTags(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"POST /api/v1/auth/refresh", "GET /api/v1/tags"}
+	if got := f.requests(); !slices.Equal(got, want) {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+	if time.Until(c.TokenExpiresAt()) < refreshMargin {
+		t.Errorf("token still expires at %v", c.TokenExpiresAt())
+	}
+}
+
+// pauseRecorder is a Limiter that records the pauses asked of it instead of
+// sleeping.
+type pauseRecorder struct {
+	pauses []time.Duration
+}
+
+func (p *pauseRecorder) Wait()                 {}
+func (p *pauseRecorder) Pause(d time.Duration) { p.pauses = append(p.pauses, d) }
+
+func TestRateLimitRetry(t *testing.T) {
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, `{"error":"Rate limit exceeded"}`, http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, `{"error":"Rate limit exceeded"}`, http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "t")
+	lim := &pauseRecorder{}
+	c.Limiter = lim
+	if _, err := c.ListTags(); err != nil {
+		t.Fatal(err)
+	}
+	// The server's Retry-After, then the doubled backoff.
+	want := []time.Duration{time.Second, 2 * initialBackoff}
+	if !slices.Equal(lim.pauses, want) {
+		t.Errorf("pauses = %v, want %v", lim.pauses, want)
+	}
+
+	n = 0
+	c.MaxRetries = 1
+	lim.pauses = nil
+	if _, err := c.ListTags(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("after MaxRetries, error = %v, want ErrRateLimited", err)
+	}
+	if len(lim.pauses) != 1 {
+		t.Errorf("paused %d times with MaxRetries 1", len(lim.pauses))
+	}
+}
@@ -0,0 +1,73 @@
+// Package credentials locates Notes API credentials outside of command-line
+// arguments: NOTES_* environment variables, a ~/.netrc entry, or a hidden
+// terminal prompt. Secrets are never included in String output, so a
+// Credentials value is safe to log.
+package credentials
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mbright/notesapi"
+)
+
+// Environment variables read by FromEnv.
+const (
+	EnvEmail    = "NOTES_EMAIL"
+	EnvPassword = "NOTES_PASSWORD"
+	EnvToken    = "NOTES_TOKEN"
+)
+
+// Credentials identify a Notes user by either a pre-issued API token or an
+// email and password.
+type Credentials struct {
+	Email    string
+	Password string
+	Token    string
+	// Source describes where the credentials came from, e.g. "NOTES_TOKEN".
+	Source string
+}
+
+// String describes the credentials without revealing the password or token.
+func (c Credentials) String() string {
+	switch {
+	case c.Token != "":
+		return fmt.Sprintf("API token from %s", c.Source)
+	case c.Email != "":
+		return fmt.Sprintf("%s (password from %s)", c.Email, c.Source)
+	}
+	return "no credentials"
+}
+
+// GoString is the same as String so that %#v cannot leak secrets either.
+func (c Credentials) GoString() string {
+	return c.String()
+}
+
+// FromEnv returns credentials from NOTES_TOKEN, or NOTES_EMAIL together with
+// NOTES_PASSWORD. The token wins if both are set.
+func FromEnv() (Credentials, bool) {
+	if tok := os.Getenv(EnvToken); tok != "" {
+		return Credentials{Token: tok, Source: EnvToken}, true
+	}
+	email, password := os.Getenv(EnvEmail), os.Getenv(EnvPassword)
+	if email != "" && password != "" {
+		return Credentials{Email: email, Password: password, Source: EnvEmail + "/" + EnvPassword}, true
+	}
+	return Credentials{}, false
+}
+
+// Login authenticates client with c: a token is installed as-is (its expiry
+// is unknown), an email and password are exchanged for a token.
+func Login(client *notesapi.Client, c Credentials) error {
+	if c.Token != "" {
+		client.SetToken(c.Token, time.Time{})
+		return nil
+	}
+	if c.Email == "" || c.Password == "" {
+		return fmt.Errorf("no Notes credentials")
+	}
+	_, err := client.Authenticate(c.Email, c.Password)
+	return err
+}
@@ -0,0 +1,123 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetrcPath returns $NETRC, or ~/.netrc.
+func NetrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// FromNetrc looks up the login and password for the host of notesURL in the
+// netrc file. If email is non-empty, only an entry with that login matches.
+// A missing netrc file is not an error.
+func FromNetrc(notesURL, email string) (Credentials, bool, error) {
+	u, err := url.Parse(notesURL)
+	if err != nil || u.Hostname() == "" {
+		return Credentials{}, false, fmt.Errorf("invalid Notes URL %q", notesURL)
+	}
+	path := NetrcPath()
+	if path == "" {
+		return Credentials{}, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Credentials{}, false, nil
+	}
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	for _, e := range parseNetrc(string(data)) {
+		if e.machine != u.Hostname() && !e.isDefault {
+			continue
+		}
+		if email != "" && e.login != email {
+			continue
+		}
+		if e.login == "" || e.password == "" {
+			continue
+		}
+		return Credentials{Email: e.login, Password: e.password, Source: path}, true, nil
+	}
+	return Credentials{}, false, nil
+}
+
+type netrcEntry struct {
+	machine   string
+	isDefault bool
+	login     string
+	password  string
+}
+
+// parseNetrc parses machine/default entries. Macro definitions are skipped
+// up to the blank line that ends them. A "default" entry always sorts last,
+// as it only applies when no machine matches.
+func parseNetrc(data string) []netrcEntry {
+	var entries []netrcEntry
+	var def *netrcEntry
+	var cur *netrcEntry
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if hash := strings.Index(line, "#"); hash >= 0 {
+			line = line[:hash]
+		}
+		fields := strings.Fields(line)
+		for j := 0; j < len(fields); j++ {
+			next := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				entries = append(entries, netrcEntry{machine: next()})
+				cur = &entries[len(entries)-1]
+			case "default":
+				def = &netrcEntry{isDefault: true}
+				cur = def
+			case "login":
+				if cur != nil {
+					cur.login = next()
+				} else {
+					next()
+				}
+			case "password":
+				if cur != nil {
+					cur.password = next()
+				} else {
+					next()
+				}
+			case "account":
+				next()
+			case "macdef":
+				// Skip the macro body: everything up to the next empty line.
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	if def != nil {
+		entries = append(entries, *def)
+	}
+	return entries
+}
@@ -0,0 +1,53 @@
+package credentials
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadPassword reads a line from f without echoing it when f is a terminal,
+// then prints the newline the user's Enter key did not. When f is not a
+// terminal (e.g. piped input) the line is read as-is. The trailing newline
+// is not returned.
+func ReadPassword(f *os.File) (string, error) {
+	if !IsTerminal(f) {
+		return readLine(f)
+	}
+
+	restore, err := disableEcho(f)
+	if err != nil {
+		return "", fmt.Errorf("disabling terminal echo: %w", err)
+	}
+	line, err := readLine(f)
+	restore()
+	fmt.Fprintln(os.Stderr)
+	return line, err
+}
+
+// readLine reads up to a newline one byte at a time, so that nothing past
+// the line is consumed from f.
+func readLine(f io.Reader) (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			sb.WriteByte(buf[0])
+		}
+		if err == io.EOF {
+			if sb.Len() == 0 {
+				return "", io.ErrUnexpectedEOF
+			}
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(sb.String(), "\r"), nil
+}
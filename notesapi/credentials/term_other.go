@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd)
+
+package credentials
+
+import (
+	"errors"
+	"os"
+)
+
+// IsTerminal reports whether f is connected to a terminal. Hidden input is
+// only implemented on Linux, macOS and FreeBSD; elsewhere input is treated as
+// a plain stream.
+func IsTerminal(f *os.File) bool {
+	return false
+}
+
+func disableEcho(f *os.File) (func(), error) {
+	return nil, errors.New("hidden input is not supported on this platform")
+}
@@ -0,0 +1,46 @@
+//go:build linux || darwin || freebsd
+
+package credentials
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	var t syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return nil, errno
+	}
+	return &t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// IsTerminal reports whether f is connected to a terminal.
+func IsTerminal(f *os.File) bool {
+	_, err := getTermios(f.Fd())
+	return err == nil
+}
+
+// disableEcho turns off echo on the terminal f and returns a function that
+// restores the previous settings.
+func disableEcho(f *os.File) (func(), error) {
+	old, err := getTermios(f.Fd())
+	if err != nil {
+		return nil, err
+	}
+	t := *old
+	t.Lflag &^= syscall.ECHO
+	t.Lflag |= syscall.ICANON | syscall.ISIG
+	if err := setTermios(f.Fd(), &t); err != nil {
+		return nil, err
+	}
+	return func() { setTermios(f.Fd(), old) }, nil
+}
@@ -0,0 +1,99 @@
+package notesapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors matched by *APIError via errors.Is.
+var (
+	ErrUnauthorized = errors.New("unauthorized")      // HTTP 401
+	ErrForbidden    = errors.New("permission denied") // HTTP 403
+	ErrNotFound     = errors.New("not found")         // HTTP 404
+	ErrValidation   = errors.New("validation failed") // HTTP 422
+	ErrRateLimited  = errors.New("rate limited")      // HTTP 429
+)
+
+// APIError is returned for any non-2xx response from the Notes API.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	// Message is the server's "error" field, or a snippet of the raw body.
+	Message string
+	// Errors holds the validation messages of a 422 response.
+	Errors []string
+	// RetryAfter is the parsed Retry-After header of a 429 response.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if len(e.Errors) > 0 {
+		msg = strings.Join(e.Errors, "; ")
+	}
+	return fmt.Sprintf("HTTP %d from %s %s: %s", e.StatusCode, e.Method, e.Path, msg)
+}
+
+// Is reports whether the error's status code corresponds to target.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrValidation:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func newAPIError(method, path string, status int, header http.Header, body []byte) *APIError {
+	e := &APIError{StatusCode: status, Method: method, Path: path}
+
+	var parsed struct {
+		Error  string   `json:"error"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && (parsed.Error != "" || len(parsed.Errors) > 0) {
+		e.Message = parsed.Error
+		e.Errors = parsed.Errors
+	} else {
+		snippet := string(body)
+		if len(snippet) > 200 {
+			snippet = snippet[:200] + "..."
+		}
+		e.Message = snippet
+	}
+
+	if header != nil {
+		e.RetryAfter = parseRetryAfter(header.Get("Retry-After"))
+	}
+	return e
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: a number of
+// seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
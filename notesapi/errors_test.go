@@ -0,0 +1,86 @@
+package notesapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	sentinels := map[int]error{
+		http.StatusUnauthorized:        ErrUnauthorized,
+		http.StatusForbidden:           ErrForbidden,
+		http.StatusNotFound:            ErrNotFound,
+		http.StatusUnprocessableEntity: ErrValidation,
+		http.StatusTooManyRequests:     ErrRateLimited,
+		http.StatusInternalServerError: nil,
+	}
+	for status, want := range sentinels {
+		// Wrapped, as the client's methods return them.
+		err := fmt.Errorf("getting note 1: %w", &APIError{StatusCode: status})
+		for _, target := range sentinels {
+			if target == nil {
+				continue
+			}
+			if got := errors.Is(err, target); got != (target == want) {
+				t.Errorf("errors.Is(HTTP %d, %v) = %v", status, target, got)
+			}
+		}
+	}
+}
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"error field", `{"error":"Note not found"}`, "HTTP 404 from GET /api/v1/notes/1: Note not found"},
+		{"validation errors", `{"errors":["Title is too long","Body can't be blank"]}`, "HTTP 404 from GET /api/v1/notes/1: Title is too long; Body can't be blank"},
+		{"not JSON", `<html>oops</html>`, "HTTP 404 from GET /api/v1/notes/1: <html>oops</html>"},
+	}
+	for _, tt := range tests {
+		err := newAPIError("GET", "/api/v1/notes/1", http.StatusNotFound, nil, []byte(tt.body))
+		if got := err.Error(); got != tt.want {
+			t.Errorf("%s: Error() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = 'x'
+	}
+	err := newAPIError("GET", "/", http.StatusBadGateway, nil, long)
+	if len(err.Message) != 203 {
+		t.Errorf("message of a 300-byte body is %d bytes, want 200 and \"...\"", len(err.Message))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("empty: %v, want 0", got)
+	}
+	if got := parseRetryAfter("30"); got != 30*time.Second {
+		t.Errorf("seconds: %v, want 30s", got)
+	}
+	if got := parseRetryAfter("-5"); got != 0 {
+		t.Errorf("negative: %v, want 0", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Errorf("garbage: %v, want 0", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 58*time.Second || got > time.Minute {
+		t.Errorf("date a minute ahead: %v, want about 1m", got)
+	}
+	past := time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(past); got != 0 {
+		t.Errorf("date in the past: %v, want 0", got)
+	}
+
+	h := http.Header{}
+	h.Set("Retry-After", "7")
+	if err := newAPIError("GET", "/", http.StatusTooManyRequests, h, nil); err.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter from header = %v, want 7s", err.RetryAfter)
+	}
+}
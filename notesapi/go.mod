@@ -0,0 +1,3 @@
+module github.com/mbright/notesapi
+
+go 1.23
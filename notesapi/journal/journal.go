@@ -0,0 +1,87 @@
+// Package journal keeps an importer's on-disk checkpoint of progress: one
+// entry per source item, recording how far its import got and which note it
+// became. The importers share it so that loading, saving and copying entries
+// behave the same; each defines the entry type it records.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/mbright/notesapi"
+)
+
+// Journal maps source items, e.g. a Keep file name or a memo name, to their
+// entries. It is rewritten after every step so that an interrupted run can
+// be resumed exactly where it stopped. It is safe for concurrent use;
+// entries are copied in and out so that workers never share one.
+type Journal[E any] struct {
+	mu      sync.Mutex
+	path    string
+	clone   func(*E) *E
+	Entries map[string]*E `json:"entries"`
+}
+
+// Open loads the journal at path, or starts an empty one if there is none
+// yet. Entries are kept across runs, so that every run knows which note each
+// source item became. clone copies an entry, including any maps it holds.
+func Open[E any](path string, clone func(*E) *E) (*Journal[E], error) {
+	j := &Journal[E]{path: path, clone: clone, Entries: make(map[string]*E)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("parsing journal %s: %w", path, err)
+	}
+	if j.Entries == nil {
+		j.Entries = make(map[string]*E)
+	}
+	return j, nil
+}
+
+// Len returns the number of entries recorded.
+func (j *Journal[E]) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.Entries)
+}
+
+// Get returns a copy of the entry for a source item, or nil if none was
+// recorded.
+func (j *Journal[E]) Get(source string) *E {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if e := j.Entries[source]; e != nil {
+		return j.clone(e)
+	}
+	return nil
+}
+
+// Record stores a copy of the entry for a source item and flushes the
+// journal to disk.
+func (j *Journal[E]) Record(source string, e *E) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Entries[source] = j.clone(e)
+	return j.save()
+}
+
+// save writes the journal atomically, so a crash mid-write never leaves a
+// truncated journal behind. The caller must hold j.mu.
+func (j *Journal[E]) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling journal: %w", err)
+	}
+	if err := notesapi.WriteFileAtomic(j.path, data); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package journal
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	NoteID int             `json:"note_id"`
+	Done   bool            `json:"done"`
+	Shares map[string]bool `json:"shares,omitempty"`
+}
+
+func (e *entry) clone() *entry {
+	c := *e
+	c.Shares = maps.Clone(e.Shares)
+	return &c
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	j, err := Open(path, (*entry).clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Len() != 0 || j.Get("a") != nil {
+		t.Fatalf("new journal has entries")
+	}
+
+	e := &entry{NoteID: 1, Shares: map[string]bool{"bob@example.com": true}}
+	if err := j.Record("a", e); err != nil {
+		t.Fatal(err)
+	}
+	// Entries are copied in and out.
+	e.Shares["eve@example.com"] = true
+	got := j.Get("a")
+	if len(got.Shares) != 1 {
+		t.Errorf("recorded entry changed with the caller's copy: %v", got.Shares)
+	}
+	got.Done = true
+	if j.Get("a").Done {
+		t.Errorf("stored entry changed with a copy from Get")
+	}
+
+	// Entries are kept across runs.
+	j2, err := Open(path, (*entry).clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j2.Len() != 1 || j2.Get("a").NoteID != 1 {
+		t.Errorf("reopened journal = %v", j2.Entries)
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*"))
+	if len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestOpenBadJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, (*entry).clone); err == nil {
+		t.Error("Open of a corrupt journal succeeded")
+	}
+}
@@ -0,0 +1,345 @@
+// Package manifest records what an import run created in Notes, so that the
+// run can be rolled back: every note, tag and attachment it created, and the
+// notes it changed in place, per Notes account. The importers share it so
+// that their rollback commands behave the same.
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mbright/notesapi"
+)
+
+// Manifest is the on-disk record of one import run. It is rewritten after
+// every change, so it stays complete if the run is interrupted. It is safe
+// for concurrent use.
+type Manifest struct {
+	mu        sync.Mutex
+	path      string
+	Tool      string     `json:"tool"`
+	NotesURL  string     `json:"notes_url"`
+	StartedAt time.Time  `json:"started_at"`
+	Accounts  []*Account `json:"accounts"`
+}
+
+// Account lists what a run created in one Notes account. A nil *Account
+// records nothing, so callers need not check whether a manifest is kept.
+type Account struct {
+	m *Manifest
+	// Name says whose account it is, e.g. the Memos user migrated into it.
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	// Notes were created by the run; Updated were changed in place, which
+	// rollback cannot undo.
+	Notes       []Note       `json:"notes"`
+	Updated     []Note       `json:"updated,omitempty"`
+	Tags        []Tag        `json:"tags"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Note is a note the run created or changed.
+type Note struct {
+	ID int `json:"id"`
+	// Source identifies what it was imported from, e.g. a memo name.
+	Source string `json:"source,omitempty"`
+}
+
+// Tag is a tag the run created.
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Attachment is a file the run uploaded to a note.
+type Attachment struct {
+	NoteID   int    `json:"note_id"`
+	Filename string `json:"filename"`
+}
+
+// Create starts the manifest of a run at path and writes it, so a path that
+// cannot be written is reported before anything is imported.
+func Create(path, tool, notesURL string) (*Manifest, error) {
+	m := &Manifest{
+		path:      path,
+		Tool:      tool,
+		NotesURL:  notesURL,
+		StartedAt: time.Now().UTC().Truncate(time.Second),
+		Accounts:  []*Account{},
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DefaultPath returns a manifest file name for a run of tool started now,
+// e.g. "gkeep-manifest-20240601T120000Z.json".
+func DefaultPath(tool string) string {
+	return fmt.Sprintf("%s-manifest-%s.json", tool, time.Now().UTC().Format("20060102T150405Z"))
+}
+
+// Load reads a manifest written by an earlier run.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	m := &Manifest{path: path}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	for _, a := range m.Accounts {
+		a.m = m
+	}
+	return m, nil
+}
+
+// Account returns the record for a Notes account, adding it if needed. A
+// nil Manifest returns a nil Account.
+func (m *Manifest) Account(name, email string) *Account {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.Accounts {
+		if a.Name == name && a.Email == email {
+			return a
+		}
+	}
+	a := &Account{m: m, Name: name, Email: email, Notes: []Note{}, Tags: []Tag{}, Attachments: []Attachment{}}
+	m.Accounts = append(m.Accounts, a)
+	return a
+}
+
+// Path returns the file the manifest is written to.
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// record applies change to the account and flushes the manifest.
+func (a *Account) record(change func()) error {
+	if a == nil {
+		return nil
+	}
+	a.m.mu.Lock()
+	defer a.m.mu.Unlock()
+	change()
+	return a.m.save()
+}
+
+// NoteCreated records a note the run created.
+func (a *Account) NoteCreated(id int, source string) error {
+	return a.record(func() { a.Notes = append(a.Notes, Note{id, source}) })
+}
+
+// NoteUpdated records an existing note the run changed.
+func (a *Account) NoteUpdated(id int, source string) error {
+	return a.record(func() { a.Updated = append(a.Updated, Note{id, source}) })
+}
+
+// TagCreated records a tag the run created.
+func (a *Account) TagCreated(id int, name string) error {
+	return a.record(func() { a.Tags = append(a.Tags, Tag{id, name}) })
+}
+
+// AttachmentUploaded records a file the run uploaded to a note.
+func (a *Account) AttachmentUploaded(noteID int, filename string) error {
+	return a.record(func() { a.Attachments = append(a.Attachments, Attachment{noteID, filename}) })
+}
+
+// Empty reports whether nothing was recorded for the account.
+func (a *Account) Empty() bool {
+	return len(a.Notes) == 0 && len(a.Updated) == 0 && len(a.Tags) == 0 && len(a.Attachments) == 0
+}
+
+// Summary counts what rollback would delete, e.g. "3 note(s), 1 tag(s) and
+// 2 attachment(s)".
+func (a *Account) Summary() string {
+	return fmt.Sprintf("%d note(s), %d tag(s) and %d attachment(s)", len(a.Notes), len(a.Tags), len(a.Attachments))
+}
+
+// String describes the account for prompts and reports.
+func (a *Account) String() string {
+	switch {
+	case a.Name != "" && a.Email != "":
+		return fmt.Sprintf("%s (%s)", a.Name, a.Email)
+	case a.Email != "":
+		return a.Email
+	case a.Name != "":
+		return a.Name
+	}
+	return "Notes account"
+}
+
+// save writes the manifest atomically via a temp file and rename. The
+// caller must hold m.mu.
+func (m *Manifest) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling manifest: %w", err)
+	}
+	if err := notesapi.WriteFileAtomic(m.path, data); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
+
+// Report is the outcome of rolling back one account.
+type Report struct {
+	NotesDeleted       int
+	AttachmentsDeleted int
+	TagsDeleted        int
+	// Failed lists the deletions that did not succeed; running the
+	// rollback again retries them.
+	Failed []string
+	// NotUndone lists changes that rollback leaves in place by design.
+	NotUndone []string
+}
+
+// Rollback undoes what a run recorded for an account, using a client
+// authenticated as that account:
+//
+//  1. attachments uploaded to notes the run did not create are removed;
+//  2. notes the run created are deleted for good, by deleting them once to
+//     move them to the trash and again to purge them;
+//  3. tags the run created are deleted if no note uses them any more.
+//
+// Notes the run updated in place are reported but left alone; their earlier
+// content is in each note's version history. What was undone, or was already
+// gone, is dropped from the manifest file, so running the rollback again
+// retries only the failures.
+func Rollback(c *notesapi.Client, a *Account, logf func(format string, args ...any)) *Report {
+	r := &Report{}
+
+	created := make(map[int]bool)
+	for _, n := range a.Notes {
+		created[n.ID] = true
+	}
+
+	var attachments []Attachment
+	for _, att := range a.Attachments {
+		if created[att.NoteID] {
+			attachments = append(attachments, att) // goes with the note
+			continue
+		}
+		deleted, err := deleteAttachment(c, att)
+		switch {
+		case err != nil:
+			r.Failed = append(r.Failed, fmt.Sprintf("attachment %s on note %d: %v", att.Filename, att.NoteID, err))
+			attachments = append(attachments, att)
+		case deleted:
+			logf("Removed attachment %s from note %d", att.Filename, att.NoteID)
+			r.AttachmentsDeleted++
+		}
+	}
+
+	var notes []Note
+	for _, n := range a.Notes {
+		if err := purgeNote(c, n.ID); err != nil {
+			r.Failed = append(r.Failed, fmt.Sprintf("note %d (%s): %v", n.ID, n.Source, err))
+			notes = append(notes, n)
+			continue
+		}
+		logf("Deleted note %d (%s)", n.ID, n.Source)
+		r.NotesDeleted++
+	}
+	// Attachments of purged notes are gone with them.
+	attachments = slices.DeleteFunc(attachments, func(att Attachment) bool {
+		return created[att.NoteID] && !slices.ContainsFunc(notes, func(n Note) bool { return n.ID == att.NoteID })
+	})
+
+	var tags []Tag
+	for _, t := range a.Tags {
+		tag, err := c.GetTag(t.ID)
+		if errors.Is(err, notesapi.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			r.Failed = append(r.Failed, fmt.Sprintf("tag %s: %v", t.Name, err))
+			tags = append(tags, t)
+			continue
+		}
+		if n := len(tag.Notes); n > 0 {
+			r.NotUndone = append(r.NotUndone, fmt.Sprintf("tag %s kept: %d other note(s) use it", t.Name, n))
+			tags = append(tags, t)
+			continue
+		}
+		if err := c.DeleteTag(t.ID); err != nil && !errors.Is(err, notesapi.ErrNotFound) {
+			r.Failed = append(r.Failed, fmt.Sprintf("tag %s: %v", t.Name, err))
+			tags = append(tags, t)
+			continue
+		}
+		logf("Deleted tag %s", t.Name)
+		r.TagsDeleted++
+	}
+
+	for _, n := range a.Updated {
+		r.NotUndone = append(r.NotUndone, fmt.Sprintf("note %d (%s) was updated in place; restore it from its version history", n.ID, n.Source))
+	}
+
+	err := a.record(func() {
+		a.Notes = nonNil(notes)
+		a.Tags = nonNil(tags)
+		a.Attachments = nonNil(attachments)
+	})
+	if err != nil {
+		r.Failed = append(r.Failed, fmt.Sprintf("updating %s: %v", a.m.path, err))
+	}
+	return r
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// purgeNote deletes a note permanently. A note that is already in the trash
+// is purged by the first delete, and one that is gone counts as deleted.
+func purgeNote(c *notesapi.Client, id int) error {
+	for range 2 {
+		err := c.DeleteNote(id)
+		if errors.Is(err, notesapi.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAttachment removes the most recent attachment of a note with the
+// recorded filename, reporting false if there is none.
+func deleteAttachment(c *notesapi.Client, att Attachment) (bool, error) {
+	list, err := c.ListAttachments(att.NoteID)
+	if errors.Is(err, notesapi.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var latest *notesapi.Attachment
+	for i, a := range list {
+		if a.Filename == att.Filename && (latest == nil || a.CreatedAt.After(latest.CreatedAt)) {
+			latest = &list[i]
+		}
+	}
+	if latest == nil {
+		return false, nil
+	}
+	if err := c.DeleteAttachment(att.NoteID, latest.ID); err != nil && !errors.Is(err, notesapi.ErrNotFound) {
+		return false, err
+	}
+	return true, nil
+}
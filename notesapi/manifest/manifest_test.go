@@ -0,0 +1,173 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbright/notesapi"
+)
+
+// fakeNotes serves the routes Rollback uses from in-memory state.
+type fakeNotes struct {
+	mu          sync.Mutex
+	notes       map[int]string // id → "active" or "trashed"
+	broken      map[int]bool   // notes whose deletion fails
+	attachments map[int][]notesapi.Attachment
+	tagNotes    map[int]int // tag id → number of notes using it
+	deleted     []string
+}
+
+func (f *fakeNotes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var note, att, tag int
+	switch {
+	case r.Method == "GET" && scan(r.URL.Path, "/api/v1/notes/%d/attachments", &note):
+		json.NewEncoder(w).Encode(f.attachments[note])
+	case r.Method == "DELETE" && scan(r.URL.Path, "/api/v1/notes/%d/attachments/%d", &note, &att):
+		f.deleted = append(f.deleted, fmt.Sprintf("attachment %d", att))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "DELETE" && scan(r.URL.Path, "/api/v1/notes/%d", &note):
+		switch {
+		case f.broken[note]:
+			http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+		case f.notes[note] == "active":
+			f.notes[note] = "trashed"
+			w.WriteHeader(http.StatusNoContent)
+		case f.notes[note] == "trashed":
+			delete(f.notes, note)
+			f.deleted = append(f.deleted, fmt.Sprintf("note %d", note))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, `{"error":"Note not found"}`, http.StatusNotFound)
+		}
+	case r.Method == "GET" && scan(r.URL.Path, "/api/v1/tags/%d", &tag):
+		n, ok := f.tagNotes[tag]
+		if !ok {
+			http.Error(w, `{"error":"Tag not found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(notesapi.Tag{ID: tag, Notes: make([]notesapi.Note, n)})
+	case r.Method == "DELETE" && scan(r.URL.Path, "/api/v1/tags/%d", &tag):
+		delete(f.tagNotes, tag)
+		f.deleted = append(f.deleted, fmt.Sprintf("tag %d", tag))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusTeapot)
+	}
+}
+
+// scan matches path against format exactly.
+func scan(path, format string, args ...any) bool {
+	n, err := fmt.Sscanf(path, format, args...)
+	return err == nil && n == len(args) && fmt.Sprintf(format, deref(args)...) == path
+}
+
+func deref(args []any) []any {
+	out := make([]any, len(args))
+	for i, a := range args {
+		out[i] = *a.(*int)
+	}
+	return out
+}
+
+func TestRollback(t *testing.T) {
+	now := time.Now()
+	f := &fakeNotes{
+		notes:  map[int]string{1: "active", 2: "trashed", 4: "active"},
+		broken: map[int]bool{4: true},
+		attachments: map[int][]notesapi.Attachment{
+			9: {
+				{ID: 90, Filename: "a.png", CreatedAt: now.Add(-time.Hour)},
+				{ID: 91, Filename: "a.png", CreatedAt: now},
+				{ID: 92, Filename: "b.png", CreatedAt: now},
+			},
+		},
+		tagNotes: map[int]int{20: 0, 21: 2},
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "m.json")
+	m, err := Create(path, "test", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := m.Account("alice", "alice@example.com")
+	for _, step := range []error{
+		a.NoteCreated(1, "memos/1"),
+		a.NoteCreated(2, "memos/2"),
+		a.NoteCreated(3, "memos/3"), // already gone
+		a.NoteCreated(4, "memos/4"), // cannot be deleted
+		a.NoteUpdated(7, "memos/7"),
+		a.AttachmentUploaded(1, "x.png"), // goes with note 1
+		a.AttachmentUploaded(4, "y.png"), // stays with note 4
+		a.AttachmentUploaded(9, "a.png"), // on a note the run did not create
+		a.AttachmentUploaded(9, "c.png"), // already gone
+		a.TagCreated(20, "unused"),
+		a.TagCreated(21, "shared"),
+		a.TagCreated(22, "gone"),
+	} {
+		if step != nil {
+			t.Fatal(step)
+		}
+	}
+
+	var log []string
+	r := Rollback(notesapi.NewClient(srv.URL, "t"), a, func(format string, args ...any) {
+		log = append(log, fmt.Sprintf(format, args...))
+	})
+
+	if r.NotesDeleted != 3 || r.AttachmentsDeleted != 1 || r.TagsDeleted != 1 {
+		t.Errorf("deleted %d notes, %d attachments, %d tags; want 3, 1, 1", r.NotesDeleted, r.AttachmentsDeleted, r.TagsDeleted)
+	}
+	sort.Strings(f.deleted)
+	if want := []string{"attachment 91", "note 1", "note 2", "tag 20"}; !slices.Equal(f.deleted, want) {
+		t.Errorf("server deleted %q, want %q", f.deleted, want)
+	}
+	if len(r.Failed) != 1 || !strings.HasPrefix(r.Failed[0], "note 4 (memos/4)") {
+		t.Errorf("Failed = %q, want note 4", r.Failed)
+	}
+	if len(r.NotUndone) != 2 || !strings.Contains(r.NotUndone[0], "tag shared kept") || !strings.Contains(r.NotUndone[1], "note 7") {
+		t.Errorf("NotUndone = %q", r.NotUndone)
+	}
+
+	// What is left is what a second rollback would retry.
+	m2, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := m2.Accounts[0]
+	if len(left.Notes) != 1 || left.Notes[0].ID != 4 {
+		t.Errorf("notes left = %v, want note 4", left.Notes)
+	}
+	if len(left.Attachments) != 1 || left.Attachments[0] != (Attachment{4, "y.png"}) {
+		t.Errorf("attachments left = %v, want y.png on note 4", left.Attachments)
+	}
+	if len(left.Tags) != 1 || left.Tags[0].ID != 21 {
+		t.Errorf("tags left = %v, want tag 21", left.Tags)
+	}
+	if len(left.Updated) != 1 {
+		t.Errorf("updated notes left = %v, want note 7", left.Updated)
+	}
+}
+
+func TestNilAccount(t *testing.T) {
+	var m *Manifest
+	a := m.Account("alice", "")
+	if a != nil {
+		t.Fatalf("Account of a nil manifest = %v", a)
+	}
+	if err := a.NoteCreated(1, "x"); err != nil {
+		t.Errorf("NoteCreated on a nil account: %v", err)
+	}
+}
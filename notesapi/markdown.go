@@ -0,0 +1,13 @@
+package notesapi
+
+import "strings"
+
+// linkTargetEscaper escapes the characters that would end a Markdown link
+// target.
+var linkTargetEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+
+// EscapeLinkTarget makes url safe as the target of a Markdown link,
+// "[text](url)", in a note body.
+func EscapeLinkTarget(url string) string {
+	return linkTargetEscaper.Replace(url)
+}
@@ -0,0 +1,15 @@
+package notesapi
+
+import "testing"
+
+func TestEscapeLinkTarget(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"https://example.com/a", "https://example.com/a"},
+		{"https://example.com/my file (1).pdf", "https://example.com/my%20file%20%281%29.pdf"},
+	}
+	for _, tt := range tests {
+		if got := EscapeLinkTarget(tt.in); got != tt.want {
+			t.Errorf("EscapeLinkTarget(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package notesapi
+
+import (
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// ListOptions selects a page of a paginated endpoint. Zero values use the
+// server defaults.
+type ListOptions struct {
+	Page  int
+	Limit int
+}
+
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+	if o.Page > 0 {
+		v.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return v
+}
+
+// ListNotesOptions filters and sorts GET /api/v1/notes.
+type ListNotesOptions struct {
+	ListOptions
+	// Filter is "" (active), "pinned", "archived" or "trash".
+	Filter string
+	// Tag restricts results to notes with the named tag.
+	Tag string
+	// Sort is "" (pinned first, then most recently updated), "created_at" or "title".
+	Sort string
+	// Direction is "asc" or "desc" (the default).
+	Direction string
+}
+
+func (o ListNotesOptions) values() url.Values {
+	v := o.ListOptions.values()
+	for key, val := range map[string]string{
+		"filter":    o.Filter,
+		"tag":       o.Tag,
+		"sort":      o.Sort,
+		"direction": o.Direction,
+	} {
+		if val != "" {
+			v.Set(key, val)
+		}
+	}
+	return v
+}
+
+func withQuery(path string, v url.Values) string {
+	if len(v) == 0 {
+		return path
+	}
+	return path + "?" + v.Encode()
+}
+
+func notePath(id int) string {
+	return fmt.Sprintf("/api/v1/notes/%d", id)
+}
+
+// ListNotes returns one page of the user's accessible notes.
+func (c *Client) ListNotes(opts ListNotesOptions) (*NoteList, error) {
+	var list NoteList
+	if err := c.doJSON("GET", withQuery("/api/v1/notes", opts.values()), nil, &list); err != nil {
+		return nil, fmt.Errorf("listing notes: %w", err)
+	}
+	return &list, nil
+}
+
+// AllNotes iterates over every note matching opts, fetching pages on demand
+// starting at opts.Page. Iteration stops after the first error.
+func (c *Client) AllNotes(opts ListNotesOptions) iter.Seq2[Note, error] {
+	return paginate(opts.Page, func(page int) (*NoteList, error) {
+		opts.Page = page
+		return c.ListNotes(opts)
+	})
+}
+
+// GetNote returns a single note.
+func (c *Client) GetNote(id int) (*Note, error) {
+	var note Note
+	if err := c.doJSON("GET", notePath(id), nil, &note); err != nil {
+		return nil, fmt.Errorf("getting note %d: %w", id, err)
+	}
+	return &note, nil
+}
+
+// CreateNote creates a note. CreatedAt and UpdatedAt, when set, preserve the
+// original timestamps of imported content.
+func (c *Client) CreateNote(params NoteParams) (*Note, error) {
+	var note Note
+	if err := c.doJSON("POST", "/api/v1/notes", params, &note); err != nil {
+		return nil, fmt.Errorf("creating note: %w", err)
+	}
+	return &note, nil
+}
+
+// UpdateNote changes the set fields of a note. A body change records a new
+// version on the server.
+func (c *Client) UpdateNote(id int, params NoteParams) (*Note, error) {
+	var note Note
+	if err := c.doJSON("PATCH", notePath(id), params, &note); err != nil {
+		return nil, fmt.Errorf("updating note %d: %w", id, err)
+	}
+	return &note, nil
+}
+
+// DeleteNote moves a note to the trash, or permanently deletes it if it is
+// already trashed. Only the owner may delete a note.
+func (c *Client) DeleteNote(id int) error {
+	if err := c.doJSON("DELETE", notePath(id), nil, nil); err != nil {
+		return fmt.Errorf("deleting note %d: %w", id, err)
+	}
+	return nil
+}
+
+// noteAction calls a member route that returns the updated note.
+func (c *Client) noteAction(method string, id int, action string, payload any) (*Note, error) {
+	var note Note
+	if err := c.doJSON(method, notePath(id)+"/"+action, payload, &note); err != nil {
+		return nil, fmt.Errorf("%s note %d: %w", action, id, err)
+	}
+	return &note, nil
+}
+
+// RestoreNote takes a note out of the trash.
+func (c *Client) RestoreNote(id int) (*Note, error) {
+	return c.noteAction("PATCH", id, "restore", nil)
+}
+
+// ArchiveNote archives a note (and unpins it).
+func (c *Client) ArchiveNote(id int) (*Note, error) {
+	return c.noteAction("PATCH", id, "archive", nil)
+}
+
+// UnarchiveNote moves an archived note back to the active list.
+func (c *Client) UnarchiveNote(id int) (*Note, error) {
+	return c.noteAction("PATCH", id, "unarchive", nil)
+}
+
+// TogglePin pins or unpins a note. Archived notes cannot be pinned.
+func (c *Client) TogglePin(id int) (*Note, error) {
+	return c.noteAction("PATCH", id, "toggle_pin", nil)
+}
+
+// ToggleChecklist switches a note between plain and checklist mode,
+// converting the body to "- [ ]" items when enabling it.
+func (c *Client) ToggleChecklist(id int) (*Note, error) {
+	return c.noteAction("PATCH", id, "toggle_checklist", nil)
+}
+
+// ToggleChecklistItem checks or unchecks the task on the given zero-based
+// body line.
+func (c *Client) ToggleChecklistItem(id, lineIndex int) (*Note, error) {
+	return c.noteAction("PATCH", id, "toggle_checklist_item", map[string]int{"line_index": lineIndex})
+}
+
+// DuplicateNote copies a note (unpinned, with its tags) into the current
+// user's notes.
+func (c *Client) DuplicateNote(id int) (*Note, error) {
+	return c.noteAction("POST", id, "duplicate", nil)
+}
+
+// MergeNotes appends the body and tags of mergeWithID to id and moves
+// mergeWithID to the trash.
+func (c *Client) MergeNotes(id, mergeWithID int) (*Note, error) {
+	return c.noteAction("POST", id, "merge", map[string]int{"merge_with_id": mergeWithID})
+}
+
+// ExportNote renders a note as Markdown.
+func (c *Client) ExportNote(id int) (*ExportFile, error) {
+	var file ExportFile
+	if err := c.doJSON("GET", notePath(id)+"/export", nil, &file); err != nil {
+		return nil, fmt.Errorf("exporting note %d: %w", id, err)
+	}
+	return &file, nil
+}
+
+// BulkExport renders the given notes (or every non-trashed note when ids is
+// empty) as Markdown files.
+func (c *Client) BulkExport(ids []int) ([]ExportFile, error) {
+	var payload any
+	if len(ids) > 0 {
+		payload = map[string][]int{"note_ids": ids}
+	}
+	var resp struct {
+		Files []ExportFile `json:"files"`
+	}
+	if err := c.doJSON("POST", "/api/v1/notes/bulk_export", payload, &resp); err != nil {
+		return nil, fmt.Errorf("bulk exporting notes: %w", err)
+	}
+	return resp.Files, nil
+}
+
+// SearchNotes runs a full-text query over non-trashed notes and returns one
+// page of results.
+func (c *Client) SearchNotes(query string, opts ListOptions) (*NoteList, error) {
+	v := opts.values()
+	v.Set("q", query)
+	var list NoteList
+	if err := c.doJSON("GET", withQuery("/api/v1/notes/search", v), nil, &list); err != nil {
+		return nil, fmt.Errorf("searching notes: %w", err)
+	}
+	return &list, nil
+}
+
+// AllSearchResults iterates over every note matching query.
+func (c *Client) AllSearchResults(query string, opts ListOptions) iter.Seq2[Note, error] {
+	return paginate(opts.Page, func(page int) (*NoteList, error) {
+		opts.Page = page
+		return c.SearchNotes(query, opts)
+	})
+}
+
+// ListTrash returns one page of the user's trashed notes.
+func (c *Client) ListTrash(opts ListOptions) (*NoteList, error) {
+	var list NoteList
+	if err := c.doJSON("GET", withQuery("/api/v1/notes/trash", opts.values()), nil, &list); err != nil {
+		return nil, fmt.Errorf("listing trash: %w", err)
+	}
+	return &list, nil
+}
+
+// AllTrash iterates over every trashed note.
+func (c *Client) AllTrash(opts ListOptions) iter.Seq2[Note, error] {
+	return paginate(opts.Page, func(page int) (*NoteList, error) {
+		opts.Page = page
+		return c.ListTrash(opts)
+	})
+}
+
+// paginate yields the notes of successive pages until the last page, an
+// empty page, or an error.
+func paginate(start int, fetch func(page int) (*NoteList, error)) iter.Seq2[Note, error] {
+	if start < 1 {
+		start = 1
+	}
+	return func(yield func(Note, error) bool) {
+		for page := start; ; page++ {
+			list, err := fetch(page)
+			if err != nil {
+				yield(Note{}, err)
+				return
+			}
+			for _, n := range list.Notes {
+				if !yield(n, nil) {
+					return
+				}
+			}
+			if page >= list.Pagination.Pages || len(list.Notes) == 0 {
+				return
+			}
+		}
+	}
+}
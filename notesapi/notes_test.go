@@ -0,0 +1,116 @@
+package notesapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// pagedNotes serves GET /api/v1/notes and /api/v1/notes/trash from notes
+// in pages of limit, failing page failPage (if non-zero) with HTTP 500.
+func pagedNotes(t *testing.T, notes []Note, limit, failPage int, pages *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		*pages = append(*pages, page)
+		if page == failPage {
+			http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+			return
+		}
+		if got := r.URL.Query().Get("filter"); r.URL.Path == "/api/v1/notes" && got != "archived" {
+			t.Errorf("filter = %q, want archived", got)
+		}
+		list := NoteList{Notes: []Note{}, Pagination: Pagination{Page: page, Limit: limit, Count: len(notes), Pages: (len(notes) + limit - 1) / limit}}
+		if start := (page - 1) * limit; start < len(notes) {
+			list.Notes = notes[start:min(start+limit, len(notes))]
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+}
+
+func noteIDs(seq func(func(Note, error) bool)) ([]int, error) {
+	var ids []int
+	for n, err := range seq {
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, n.ID)
+	}
+	return ids, nil
+}
+
+func TestAllNotes(t *testing.T) {
+	notes := make([]Note, 5)
+	for i := range notes {
+		notes[i].ID = i + 1
+	}
+
+	var pages []int
+	srv := pagedNotes(t, notes, 2, 0, &pages)
+	defer srv.Close()
+	c := NewClient(srv.URL, "t")
+
+	ids, err := noteIDs(c.AllNotes(ListNotesOptions{Filter: "archived", ListOptions: ListOptions{Limit: 2}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5}; !slices.Equal(ids, want) {
+		t.Errorf("notes = %v, want %v", ids, want)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(pages, want) {
+		t.Errorf("pages fetched = %v, want %v", pages, want)
+	}
+
+	// Starting part-way, and stopping early, fetches no more than needed.
+	pages = nil
+	for n, err := range c.AllNotes(ListNotesOptions{Filter: "archived", ListOptions: ListOptions{Page: 2, Limit: 2}}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.ID != 3 {
+			t.Errorf("first note from page 2 = %d, want 3", n.ID)
+		}
+		break
+	}
+	if want := []int{2}; !slices.Equal(pages, want) {
+		t.Errorf("pages fetched = %v, want %v", pages, want)
+	}
+}
+
+func TestAllTrashError(t *testing.T) {
+	notes := make([]Note, 5)
+	for i := range notes {
+		notes[i].ID = i + 1
+	}
+	var pages []int
+	srv := pagedNotes(t, notes, 2, 2, &pages)
+	defer srv.Close()
+	c := NewClient(srv.URL, "t")
+
+	ids, err := noteIDs(c.AllTrash(ListOptions{Limit: 2}))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("error = %v, want HTTP 500", err)
+	}
+	if want := []int{1, 2}; !slices.Equal(ids, want) {
+		t.Errorf("notes before the error = %v, want %v", ids, want)
+	}
+}
+
+func TestAllNotesEmpty(t *testing.T) {
+	var pages []int
+	srv := pagedNotes(t, nil, 2, 0, &pages)
+	defer srv.Close()
+	c := NewClient(srv.URL, "t")
+
+	ids, err := noteIDs(c.AllNotes(ListNotesOptions{Filter: "archived"}))
+	if err != nil || len(ids) != 0 {
+		t.Errorf("AllNotes = %v, %v; want none", ids, err)
+	}
+	if want := []int{1}; !slices.Equal(pages, want) {
+		t.Errorf("pages fetched = %v, want %v", pages, want)
+	}
+}
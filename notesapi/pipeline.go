@@ -0,0 +1,54 @@
+package notesapi
+
+import "sync"
+
+// RunOrdered calls work(i) for every i in [0, n) on up to workers goroutines
+// and hands each result to done on the calling goroutine in index order, so
+// log output and tallies are the same whatever order the work finishes in.
+// Both importers use it to migrate notes in parallel.
+func RunOrdered[T any](n, workers int, work func(i int) T, done func(i int, r T)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type result struct {
+		i int
+		r T
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results <- result{i, work(i)}
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < n; i++ {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]T)
+	next := 0
+	for res := range results {
+		pending[res.i] = res.r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			done(next, r)
+			next++
+		}
+	}
+}
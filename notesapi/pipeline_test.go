@@ -0,0 +1,54 @@
+package notesapi
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunOrdered(t *testing.T) {
+	const n = 20
+	for _, workers := range []int{0, 1, 4, 50} {
+		var running, most atomic.Int32
+		var order []int
+		RunOrdered(n, workers, func(i int) int {
+			cur := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := most.Load()
+				if cur <= m || most.CompareAndSwap(m, cur) {
+					break
+				}
+			}
+			// Later items finish first.
+			time.Sleep(time.Duration(n-i) * 200 * time.Microsecond)
+			return i * i
+		}, func(i, r int) {
+			if r != i*i {
+				t.Errorf("workers=%d: done(%d, %d), want result %d", workers, i, r, i*i)
+			}
+			order = append(order, i)
+		})
+
+		want := make([]int, n)
+		for i := range want {
+			want[i] = i
+		}
+		if !slices.Equal(order, want) {
+			t.Errorf("workers=%d: done called in order %v, want %v", workers, order, want)
+		}
+		if limit := int32(max(workers, 1)); most.Load() > limit {
+			t.Errorf("workers=%d: %d ran at once", workers, most.Load())
+		}
+	}
+}
+
+func TestRunOrderedNone(t *testing.T) {
+	RunOrdered(0, 4, func(int) int {
+		t.Error("work called with no items")
+		return 0
+	}, func(int, int) {
+		t.Error("done called with no items")
+	})
+}
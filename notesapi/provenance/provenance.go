@@ -0,0 +1,329 @@
+// Package provenance records where an imported note came from, both in its
+// body and as an imported/<source> tag, and finds imported notes again. It
+// is shared by the importers so that notes from either can be audited the
+// same way.
+package provenance
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mbright/notesapi"
+)
+
+// Styles of embedding provenance in a note body.
+const (
+	// None leaves the body alone; the tag is not added either.
+	None = "none"
+	// Trailer appends the provenance as visible "Key: value" lines under a
+	// horizontal rule.
+	Trailer = "trailer"
+	// FrontMatter puts the provenance at the top of the body inside an HTML
+	// comment, which Notes does not render. An importer may put other front
+	// matter, such as a YAML block, before it. Checklist notes show every
+	// line that is not a task, comments included, so EmbedAfter puts it
+	// after their list instead.
+	FrontMatter = "front-matter"
+)
+
+// TagPrefix starts the name of the tag given to every imported note.
+const TagPrefix = "imported/"
+
+const (
+	frontMatterStart = "<!-- provenance\n"
+	frontMatterEnd   = "-->\n"
+	trailerStart     = "---\nSource: "
+)
+
+// Provenance describes the origin of an imported note.
+type Provenance struct {
+	// Source is the system the note came from, e.g. "memos" or "google-keep".
+	Source string
+	// SourceID identifies the note there, e.g. "memos/abc123" or the name of
+	// a Keep JSON file.
+	SourceID   string
+	ImportedAt time.Time
+	// Importer names the tool and version that imported the note.
+	Importer string
+}
+
+// Tag returns the name of the tag for notes imported from source.
+func Tag(source string) string {
+	return TagPrefix + source
+}
+
+// CheckStyle returns an error unless style is None, Trailer or FrontMatter.
+func CheckStyle(style string) error {
+	switch style {
+	case None, Trailer, FrontMatter:
+		return nil
+	}
+	return fmt.Errorf("invalid provenance style %q: want none, trailer or front-matter", style)
+}
+
+// Importer returns name followed by the version it was built as: the module
+// version, or the VCS revision of a build from a checkout.
+func Importer(name string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return name
+	}
+	version := info.Main.Version
+	if version == "" || version == "(devel)" {
+		version = "(devel)"
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+				version = s.Value[:12]
+			}
+		}
+	}
+	return name + " " + version
+}
+
+func (p Provenance) fields() [][2]string {
+	return [][2]string{
+		{"Source", p.Source},
+		{"Source-ID", p.SourceID},
+		{"Imported-At", p.ImportedAt.UTC().Format(time.RFC3339)},
+		{"Importer", p.Importer},
+	}
+}
+
+// Embed returns body with p added in the given style.
+func Embed(body string, p Provenance, style string) string {
+	var sb strings.Builder
+	for _, f := range p.fields() {
+		sb.WriteString(f[0] + ": " + oneLine(f[1]) + "\n")
+	}
+	switch style {
+	case Trailer:
+		if body != "" {
+			// The blank line keeps the rule from turning the last line of
+			// the body into a heading.
+			body = strings.TrimRight(body, "\n") + "\n\n"
+		}
+		return body + "---\n" + strings.TrimSuffix(sb.String(), "\n")
+	case FrontMatter:
+		return frontMatterStart + sb.String() + frontMatterEnd + body
+	}
+	return body
+}
+
+// EmbedAfter is Embed for checklist notes: in the FrontMatter style the
+// block goes after the body, below the list, rather than above it. Parse,
+// Strip and Edit find it in either place.
+func EmbedAfter(body string, p Provenance, style string) string {
+	if style != FrontMatter || body == "" {
+		return Embed(body, p, style)
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + Embed("", p, style)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Parse finds the provenance embedded in a note body.
+func Parse(body string) (Provenance, bool) {
+	block, _, ok := split(body)
+	if !ok {
+		return Provenance{}, false
+	}
+	var p Provenance
+	for _, line := range strings.Split(block, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSuffix(line, "\r"), ": ")
+		switch key {
+		case "Source":
+			p.Source = value
+		case "Source-ID":
+			p.SourceID = value
+		case "Imported-At":
+			p.ImportedAt, _ = time.Parse(time.RFC3339, value)
+		case "Importer":
+			p.Importer = value
+		}
+	}
+	return p, p.Source != ""
+}
+
+// Strip returns body without its embedded provenance.
+func Strip(body string) string {
+	_, rest, ok := split(body)
+	if !ok {
+		return body
+	}
+	return rest
+}
+
+// Edit returns body with edit applied to the text that follows front-matter
+// provenance, or precedes a trailer or front matter put after a checklist,
+// leaving the provenance as it was. A body without provenance is edited
+// whole.
+func Edit(body string, edit func(string) string) string {
+	if i, end, ok := findFrontMatter(body); ok {
+		if i > 0 && strings.TrimSpace(body[end:]) == "" {
+			// After a checklist, as EmbedAfter puts it.
+			if edited := strings.TrimRight(edit(strings.TrimSuffix(body[:i], "\n\n")), "\n"); edited != "" {
+				return edited + "\n\n" + body[i:]
+			}
+			return body[i:]
+		}
+		return body[:end] + edit(body[end:])
+	}
+
+	rest, block, ok := findTrailer(body)
+	if !ok {
+		return edit(body)
+	}
+	trailer := "---\n" + block
+	if edited := strings.TrimRight(edit(rest), "\n"); edited != "" {
+		return edited + "\n\n" + trailer
+	}
+	return trailer
+}
+
+// findTrailer splits body into the text before its provenance trailer and
+// the trailer's lines. Only an exact final block of the provenance lines,
+// in the order Embed writes them, is a trailer, so a note that ends with a
+// rule and a "Source:" line of its own is left alone.
+func findTrailer(body string) (rest, block string, ok bool) {
+	switch i := strings.LastIndex(body, "\n\n"+trailerStart); {
+	case i >= 0:
+		rest, block = body[:i], body[i+len("\n\n---\n"):]
+	case strings.HasPrefix(body, trailerStart):
+		rest, block = "", body[len("---\n"):]
+	default:
+		return "", "", false
+	}
+
+	fields := Provenance{}.fields()
+	lines := strings.Split(strings.TrimRight(block, "\r\n"), "\n")
+	if len(lines) != len(fields) {
+		return "", "", false
+	}
+	for i, line := range lines {
+		value, found := strings.CutPrefix(strings.TrimSuffix(line, "\r"), fields[i][0]+":")
+		if !found || value != "" && value[0] != ' ' {
+			return "", "", false
+		}
+	}
+	return rest, block, true
+}
+
+// findFrontMatter finds the front-matter provenance block of body, which
+// starts a line, and returns where it starts and ends.
+func findFrontMatter(body string) (start, end int, ok bool) {
+	i := strings.Index(body, frontMatterStart)
+	if i < 0 || i > 0 && body[i-1] != '\n' {
+		return 0, 0, false
+	}
+	j := strings.Index(body[i+len(frontMatterStart):], frontMatterEnd)
+	if j < 0 {
+		return 0, 0, false
+	}
+	return i, i + len(frontMatterStart) + j + len(frontMatterEnd), true
+}
+
+// split separates the provenance lines of body from the rest of it.
+func split(body string) (block, rest string, ok bool) {
+	if i, end, ok := findFrontMatter(body); ok {
+		block = body[i+len(frontMatterStart) : end-len(frontMatterEnd)]
+		if i > 0 && strings.TrimSpace(body[end:]) == "" {
+			// After a checklist, as EmbedAfter puts it.
+			return block, strings.TrimSuffix(body[:i], "\n\n"), true
+		}
+		return block, body[:i] + body[end:], true
+	}
+
+	if rest, block, ok := findTrailer(body); ok {
+		return block, rest, true
+	}
+	return "", body, false
+}
+
+// Note is an imported note found by Find.
+type Note struct {
+	notesapi.Note
+	// Provenance is parsed from the body. If the body has none, only
+	// Source is set, from the note's imported/ tag.
+	Provenance Provenance
+}
+
+// Find returns every active, archived and trashed note of the account that
+// has provenance in its body or an imported/ tag, ordered by source, import
+// time and source ID.
+func Find(c *notesapi.Client) ([]Note, error) {
+	var found []Note
+	seen := make(map[int]bool)
+	for _, filter := range []string{"", "archived", "trash"} {
+		opts := notesapi.ListNotesOptions{Filter: filter, ListOptions: notesapi.ListOptions{Limit: 100}}
+		for n, err := range c.AllNotes(opts) {
+			if err != nil {
+				return nil, fmt.Errorf("listing notes (filter=%q): %w", filter, err)
+			}
+			if seen[n.ID] {
+				continue
+			}
+			seen[n.ID] = true
+
+			p, ok := Parse(n.Body)
+			if !ok {
+				for _, t := range n.Tags {
+					if source, isImported := strings.CutPrefix(t.Name, TagPrefix); isImported {
+						p, ok = Provenance{Source: source}, true
+						break
+					}
+				}
+			}
+			if ok {
+				found = append(found, Note{Note: n, Provenance: p})
+			}
+		}
+	}
+
+	slices.SortFunc(found, func(a, b Note) int {
+		if c := strings.Compare(a.Provenance.Source, b.Provenance.Source); c != 0 {
+			return c
+		}
+		if c := a.Provenance.ImportedAt.Compare(b.Provenance.ImportedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Provenance.SourceID, b.Provenance.SourceID)
+	})
+	return found, nil
+}
+
+// Write prints notes as a table, one per line.
+func Write(w io.Writer, notes []Note) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tSOURCE ID\tNOTE\tIMPORTED\tIMPORTER\tTITLE")
+	for _, n := range notes {
+		p := n.Provenance
+		imported := "-"
+		if !p.ImportedAt.IsZero() {
+			imported = p.ImportedAt.UTC().Format(time.RFC3339)
+		}
+		state := ""
+		switch {
+		case n.Trashed:
+			state = " (trashed)"
+		case n.Archived:
+			state = " (archived)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d%s\t%s\t%s\t%s\n",
+			p.Source, dash(p.SourceID), n.ID, state, imported, dash(p.Importer), n.Title)
+	}
+	return tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package provenance
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var sample = Provenance{
+	Source:     "memos",
+	SourceID:   "memos/abc123",
+	ImportedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	Importer:   "import-memos v1.2.0",
+}
+
+func TestTrailerRoundTrip(t *testing.T) {
+	for _, body := range []string{"", "Hello", "Hello\n\n---\n\nmore\n"} {
+		embedded := Embed(body, sample, Trailer)
+		p, ok := Parse(embedded)
+		if !ok || p != sample {
+			t.Errorf("Parse(Embed(%q)) = %+v, %v; want %+v", body, p, ok, sample)
+		}
+		if got := Strip(embedded); strings.TrimRight(got, "\n") != strings.TrimRight(body, "\n") {
+			t.Errorf("Strip(Embed(%q)) = %q", body, got)
+		}
+	}
+}
+
+// A note of the user's own that ends like a trailer is not one.
+func TestNotATrailer(t *testing.T) {
+	for _, body := range []string{
+		"My essay\n\n---\nSource: the library",
+		"My essay\n\n---\nSource: a\nSource-ID: b\nImported-At: c",
+		"My essay\n\n---\nSource: a\nSource-ID: b\nImported-At: c\nImporter: d\nThanks for reading",
+		"---\nSource: a\nImporter: d\nSource-ID: b\nImported-At: c",
+		"My essay\n\n---\nSource: a\nSource-ID: b\nImported-At: c\nImporter: d\n\nP.S.",
+	} {
+		if p, ok := Parse(body); ok {
+			t.Errorf("Parse(%q) = %+v, want none", body, p)
+		}
+		if got := Strip(body); got != body {
+			t.Errorf("Strip(%q) = %q, want it unchanged", body, got)
+		}
+		edited := Edit(body, func(s string) string { return s + "\n\n## References\n" })
+		if want := body + "\n\n## References\n"; edited != want {
+			t.Errorf("Edit(%q) = %q, want %q", body, edited, want)
+		}
+	}
+}
+
+func TestEditKeepsTrailer(t *testing.T) {
+	body := Embed("Hello\n\n---\nSource: the library", sample, Trailer)
+	got := Edit(body, func(s string) string { return s + "\n\n## References\n" })
+	want := Embed("Hello\n\n---\nSource: the library\n\n## References\n", sample, Trailer)
+	if got != want {
+		t.Errorf("Edit = %q, want %q", got, want)
+	}
+}
+
+func TestFrontMatterRoundTrip(t *testing.T) {
+	embedded := Embed("Hello", sample, FrontMatter)
+	if p, ok := Parse(embedded); !ok || p != sample {
+		t.Errorf("Parse = %+v, %v; want %+v", p, ok, sample)
+	}
+	if got := Strip(embedded); got != "Hello" {
+		t.Errorf("Strip = %q, want %q", got, "Hello")
+	}
+}
+
+func TestFrontMatterAfterChecklist(t *testing.T) {
+	list := "- [ ] milk\n- [x] eggs\n"
+	embedded := EmbedAfter(list, sample, FrontMatter)
+	if !strings.HasPrefix(embedded, list+"\n<!-- provenance\n") {
+		t.Errorf("EmbedAfter = %q, want the list first", embedded)
+	}
+	if p, ok := Parse(embedded); !ok || p != sample {
+		t.Errorf("Parse = %+v, %v; want %+v", p, ok, sample)
+	}
+	if got, want := Strip(embedded), strings.TrimSuffix(list, "\n"); got != want {
+		t.Errorf("Strip = %q, want %q", got, want)
+	}
+	got := Edit(embedded, func(s string) string { return s + "\n- [ ] bread" })
+	if want := EmbedAfter(list+"- [ ] bread", sample, FrontMatter); got != want {
+		t.Errorf("Edit = %q, want %q", got, want)
+	}
+	if got := EmbedAfter(list, sample, Trailer); got != Embed(list, sample, Trailer) {
+		t.Errorf("EmbedAfter(Trailer) = %q, want it as Embed puts it", got)
+	}
+}
@@ -0,0 +1,88 @@
+package notesapi
+
+import (
+	"sync"
+	"time"
+)
+
+// Default request budget, matching the server's Rack::Attack throttle the
+// importers were written against: 300 requests per 5 minutes.
+const (
+	DefaultRateLimit  = 300
+	DefaultRateWindow = 5 * time.Minute
+)
+
+// TokenBucket is a Limiter shared by any number of clients and goroutines.
+// It allows short bursts and otherwise refills at a steady rate, sized so
+// that no interval of length window ever sees more than limit requests —
+// the guarantee a fixed-window server throttle needs.
+//
+// When a response carries Retry-After, the client calls Pause and every
+// goroutine waiting on the bucket holds off until the server is ready again.
+type TokenBucket struct {
+	mu          sync.Mutex
+	capacity    float64
+	tokens      float64
+	rate        float64 // tokens per second
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// NewTokenBucket creates a bucket admitting at most limit requests in any
+// window. A tenth of the budget (at least one request) may be used as an
+// immediate burst; the rest is spread evenly across the window.
+func NewTokenBucket(limit int, window time.Duration) *TokenBucket {
+	if limit < 1 {
+		limit = 1
+	}
+	burst := max(limit/10, 1)
+	rate := float64(limit-burst) / window.Seconds()
+	if limit == burst {
+		rate = float64(limit) / window.Seconds()
+	}
+	return &TokenBucket{
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		rate:     rate,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent.
+func (b *TokenBucket) Wait() {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		if now.Before(b.pausedUntil) {
+			d := b.pausedUntil.Sub(now)
+			b.mu.Unlock()
+			time.Sleep(d)
+			continue
+		}
+
+		b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		d := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		time.Sleep(d)
+	}
+}
+
+// Pause stops the bucket from admitting requests for d and drains any
+// accumulated burst, so that waiting goroutines resume gradually.
+func (b *TokenBucket) Pause(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until := time.Now().Add(d)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	// Refill from the end of the pause, not across it.
+	b.tokens = 0
+	b.last = b.pausedUntil
+}
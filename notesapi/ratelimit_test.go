@@ -0,0 +1,54 @@
+package notesapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	// A burst of 10, then 90 a second.
+	b := NewTokenBucket(100, time.Second)
+	start := time.Now()
+	for range 10 {
+		b.Wait()
+	}
+	if d := time.Since(start); d > 5*time.Millisecond {
+		t.Errorf("burst of 10 took %v, want it immediate", d)
+	}
+	b.Wait()
+	if d := time.Since(start); d < 8*time.Millisecond {
+		t.Errorf("request after the burst came %v after the start, want about 11ms", d)
+	}
+}
+
+func TestTokenBucketSingleRequestWindow(t *testing.T) {
+	b := NewTokenBucket(1, 50*time.Millisecond)
+	start := time.Now()
+	b.Wait()
+	b.Wait()
+	if d := time.Since(start); d < 45*time.Millisecond {
+		t.Errorf("two requests with a limit of 1 took %v, want at least a window", d)
+	}
+}
+
+func TestTokenBucketPause(t *testing.T) {
+	// A burst of 2, then 90 a second.
+	b := NewTokenBucket(20, 200*time.Millisecond)
+	start := time.Now()
+	b.Pause(50 * time.Millisecond)
+	b.Wait()
+	// The burst drained, and nothing refilled during the pause: the first
+	// request waits for the pause and then for one token.
+	if d := time.Since(start); d < 58*time.Millisecond {
+		t.Errorf("first request after a 50ms pause came after %v, want about 61ms", d)
+	}
+
+	// A shorter pause does not cut a longer one short.
+	start = time.Now()
+	b.Pause(40 * time.Millisecond)
+	b.Pause(10 * time.Millisecond)
+	b.Wait()
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("request came %v into a 40ms pause", d)
+	}
+}
@@ -0,0 +1,31 @@
+package notesapi
+
+import "fmt"
+
+// ListShares returns the shares of a note.
+func (c *Client) ListShares(noteID int) ([]Share, error) {
+	var shares []Share
+	if err := c.doJSON("GET", notePath(noteID)+"/shares", nil, &shares); err != nil {
+		return nil, fmt.Errorf("listing shares of note %d: %w", noteID, err)
+	}
+	return shares, nil
+}
+
+// CreateShare gives the user with the given email read-write access to a
+// note. Only the owner may share; an unknown email yields ErrNotFound.
+func (c *Client) CreateShare(noteID int, email string) (*Share, error) {
+	var share Share
+	if err := c.doJSON("POST", notePath(noteID)+"/shares", map[string]string{"email": email}, &share); err != nil {
+		return nil, fmt.Errorf("sharing note %d with %s: %w", noteID, email, err)
+	}
+	return &share, nil
+}
+
+// DeleteShare revokes a share.
+func (c *Client) DeleteShare(noteID, shareID int) error {
+	path := fmt.Sprintf("%s/shares/%d", notePath(noteID), shareID)
+	if err := c.doJSON("DELETE", path, nil, nil); err != nil {
+		return fmt.Errorf("revoking share %d of note %d: %w", shareID, noteID, err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package notesapi
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// DefaultTagColor is the server's default tag color, a gray.
+const DefaultTagColor = "#6b7280"
+
+func tagPath(id int) string {
+	return fmt.Sprintf("/api/v1/tags/%d", id)
+}
+
+// ListTags returns all of the user's tags ordered by name.
+func (c *Client) ListTags() ([]Tag, error) {
+	var tags []Tag
+	if err := c.doJSON("GET", "/api/v1/tags", nil, &tags); err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	return tags, nil
+}
+
+// GetTag returns a tag together with its notes.
+func (c *Client) GetTag(id int) (*Tag, error) {
+	var tag Tag
+	if err := c.doJSON("GET", tagPath(id), nil, &tag); err != nil {
+		return nil, fmt.Errorf("getting tag %d: %w", id, err)
+	}
+	return &tag, nil
+}
+
+// CreateTag creates a tag. The server stores names lowercased; color is a
+// "#rrggbb" hex string or empty for the default gray.
+func (c *Client) CreateTag(name, color string) (*Tag, error) {
+	var tag Tag
+	if err := c.doJSON("POST", "/api/v1/tags", TagParams{Name: name, Color: color}, &tag); err != nil {
+		return nil, fmt.Errorf("creating tag %q: %w", name, err)
+	}
+	return &tag, nil
+}
+
+// UpdateTag renames or recolors a tag.
+func (c *Client) UpdateTag(id int, params TagParams) (*Tag, error) {
+	var tag Tag
+	if err := c.doJSON("PATCH", tagPath(id), params, &tag); err != nil {
+		return nil, fmt.Errorf("updating tag %d: %w", id, err)
+	}
+	return &tag, nil
+}
+
+// DeleteTag deletes a tag, removing it from all notes.
+func (c *Client) DeleteTag(id int) error {
+	if err := c.doJSON("DELETE", tagPath(id), nil, nil); err != nil {
+		return fmt.Errorf("deleting tag %d: %w", id, err)
+	}
+	return nil
+}
+
+// TagSet maps lowercased tag names to IDs and creates missing tags on first
+// use. The server lowercases tag names, so matching is case-insensitive. The
+// importers use it so that a tag is created once however many notes carry
+// it. It is safe for concurrent use.
+type TagSet struct {
+	mu      sync.Mutex
+	client  *Client
+	ids     map[string]int
+	created int
+	// OnCreate, if set, is called with every tag Ensure creates, e.g. to
+	// record it for rollback. An error it returns is returned by Ensure.
+	OnCreate func(Tag) error
+}
+
+// FetchTags returns a TagSet holding the user's existing tags.
+func (c *Client) FetchTags() (*TagSet, error) {
+	tags, err := c.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	t := &TagSet{client: c, ids: make(map[string]int, len(tags))}
+	for _, tag := range tags {
+		t.ids[tagKey(tag.Name)] = tag.ID
+	}
+	return t, nil
+}
+
+func tagKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ID returns the ID of the named tag, if it exists.
+func (t *TagSet) ID(name string) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id, ok := t.ids[tagKey(name)]
+	return id, ok
+}
+
+// IDs returns a copy of the map from lowercased tag names to IDs.
+func (t *TagSet) IDs() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.ids)
+}
+
+// Created returns the number of tags Ensure created.
+func (t *TagSet) Created() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.created
+}
+
+// Ensure returns the ID of the named tag, creating it with color if needed.
+func (t *TagSet) Ensure(name, color string) (int, error) {
+	key := tagKey(name)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id, ok := t.ids[key]; ok {
+		return id, nil
+	}
+	tag, err := t.client.CreateTag(key, color)
+	if err != nil {
+		return 0, err
+	}
+	t.ids[key] = tag.ID
+	t.created++
+	if t.OnCreate != nil {
+		if err := t.OnCreate(*tag); err != nil {
+			return 0, err
+		}
+	}
+	return tag.ID, nil
+}
@@ -0,0 +1,63 @@
+package notesapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTagSet(t *testing.T) {
+	var created []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`[{"id":1,"name":"work"}]`))
+		case "POST":
+			var p TagParams
+			json.NewDecoder(r.Body).Decode(&p)
+			created = append(created, p.Name)
+			json.NewEncoder(w).Encode(Tag{ID: 10 + len(created), Name: p.Name, Color: p.Color})
+		}
+	}))
+	defer srv.Close()
+
+	tags, err := NewClient(srv.URL, "t").FetchTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded []Tag
+	tags.OnCreate = func(tag Tag) error {
+		recorded = append(recorded, tag)
+		return nil
+	}
+
+	for _, tt := range []struct {
+		name string
+		want int
+	}{{"Work", 1}, {" Travel ", 11}, {"travel", 11}, {"TRAVEL", 11}} {
+		id, err := tags.Ensure(tt.name, DefaultTagColor)
+		if err != nil || id != tt.want {
+			t.Errorf("Ensure(%q) = %d, %v; want %d", tt.name, id, err, tt.want)
+		}
+	}
+	if len(created) != 1 || created[0] != "travel" {
+		t.Errorf("created %q, want just \"travel\"", created)
+	}
+	if tags.Created() != 1 || len(recorded) != 1 || recorded[0].ID != 11 {
+		t.Errorf("Created() = %d, OnCreate got %v", tags.Created(), recorded)
+	}
+	if id, ok := tags.ID("WORK"); !ok || id != 1 {
+		t.Errorf("ID(WORK) = %d, %v", id, ok)
+	}
+	if ids := tags.IDs(); len(ids) != 2 || ids["travel"] != 11 {
+		t.Errorf("IDs() = %v", ids)
+	}
+
+	failed := errors.New("disk full")
+	tags.OnCreate = func(Tag) error { return failed }
+	if _, err := tags.Ensure("new", ""); !errors.Is(err, failed) {
+		t.Errorf("Ensure with failing OnCreate = %v, want %v", err, failed)
+	}
+}
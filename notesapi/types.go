@@ -0,0 +1,179 @@
+package notesapi
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Token is the response of the auth/token and auth/refresh endpoints.
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// User is the public profile of a Notes user, as embedded in shares.
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Tag is a user's note label.
+type Tag struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Notes is only populated by GetTag.
+	Notes []Note `json:"notes,omitempty"`
+}
+
+// TagParams are the writable fields of a tag. Empty fields are left unchanged
+// on update.
+type TagParams struct {
+	Name  string `json:"name,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+// Note is a note as returned by the notes endpoints.
+type Note struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	Pinned      bool       `json:"pinned"`
+	Archived    bool       `json:"archived"`
+	Trashed     bool       `json:"trashed"`
+	TrashedAt   *time.Time `json:"trashed_at"`
+	Checklist   bool       `json:"checklist"`
+	MaxSize     int        `json:"max_size"`
+	UserID      int        `json:"user_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Tags        []Tag      `json:"tags"`
+	SharedUsers []User     `json:"shared_users"`
+}
+
+// NoteParams are the writable fields of a note for CreateNote and UpdateNote.
+// Nil fields are not sent, so UpdateNote leaves them unchanged. A nil TagIDs
+// leaves tags untouched; an empty non-nil slice removes every tag.
+type NoteParams struct {
+	Title     *string
+	Body      *string
+	Pinned    *bool
+	Checklist *bool
+	MaxSize   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	TagIDs    []int
+}
+
+// MarshalJSON encodes only the fields that are set.
+func (p NoteParams) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any)
+	if p.Title != nil {
+		m["title"] = *p.Title
+	}
+	if p.Body != nil {
+		m["body"] = *p.Body
+	}
+	if p.Pinned != nil {
+		m["pinned"] = *p.Pinned
+	}
+	if p.Checklist != nil {
+		m["checklist"] = *p.Checklist
+	}
+	if p.MaxSize > 0 {
+		m["max_size"] = p.MaxSize
+	}
+	if !p.CreatedAt.IsZero() {
+		m["created_at"] = p.CreatedAt.Format(time.RFC3339)
+	}
+	if !p.UpdatedAt.IsZero() {
+		m["updated_at"] = p.UpdatedAt.Format(time.RFC3339)
+	}
+	if p.TagIDs != nil {
+		m["tag_ids"] = p.TagIDs
+	}
+	return json.Marshal(m)
+}
+
+// String returns a pointer to s, for NoteParams fields.
+func String(s string) *string { return &s }
+
+// Bool returns a pointer to b, for NoteParams fields.
+func Bool(b bool) *bool { return &b }
+
+// Pagination is the page metadata of paginated list endpoints.
+type Pagination struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Pages int `json:"pages"`
+	Count int `json:"count"`
+}
+
+// NoteList is one page of notes.
+type NoteList struct {
+	Notes      []Note     `json:"notes"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ExportFile is a note rendered as a Markdown file.
+type ExportFile struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+// Share grants another user read-write access to a note.
+type Share struct {
+	ID         int       `json:"id"`
+	NoteID     int       `json:"note_id"`
+	UserID     int       `json:"user_id"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	User       User      `json:"user"`
+}
+
+// Version is a snapshot of a note taken before its body changed.
+type Version struct {
+	ID            int       `json:"id"`
+	NoteID        int       `json:"note_id"`
+	Title         string    `json:"title"`
+	Body          string    `json:"body"`
+	VersionNumber int       `json:"version_number"`
+	Metadata      string    `json:"metadata"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// DiffFromCurrent is only populated by GetVersion.
+	DiffFromCurrent *VersionDiff `json:"diff_from_current,omitempty"`
+}
+
+// VersionDiff compares a version with the note's current content.
+type VersionDiff struct {
+	Title struct {
+		Was string `json:"was"`
+		Now string `json:"now"`
+	} `json:"title"`
+	Body struct {
+		Was string `json:"was"`
+		Now string `json:"now"`
+	} `json:"body"`
+}
+
+// Attachment is the metadata of a file attached to a note.
+type Attachment struct {
+	ID          int       `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	ByteSize    int64     `json:"byte_size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// File is a file to upload as an attachment.
+type File struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
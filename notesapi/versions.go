@@ -0,0 +1,34 @@
+package notesapi
+
+import "fmt"
+
+func versionPath(noteID, versionID int) string {
+	return fmt.Sprintf("%s/versions/%d", notePath(noteID), versionID)
+}
+
+// ListVersions returns a note's versions, newest first.
+func (c *Client) ListVersions(noteID int) ([]Version, error) {
+	var versions []Version
+	if err := c.doJSON("GET", notePath(noteID)+"/versions", nil, &versions); err != nil {
+		return nil, fmt.Errorf("listing versions of note %d: %w", noteID, err)
+	}
+	return versions, nil
+}
+
+// GetVersion returns a version together with its diff from the current note.
+func (c *Client) GetVersion(noteID, versionID int) (*Version, error) {
+	var v Version
+	if err := c.doJSON("GET", versionPath(noteID, versionID), nil, &v); err != nil {
+		return nil, fmt.Errorf("getting version %d of note %d: %w", versionID, noteID, err)
+	}
+	return &v, nil
+}
+
+// RestoreVersion replaces a note's title and body with those of a version.
+func (c *Client) RestoreVersion(noteID, versionID int) (*Note, error) {
+	var note Note
+	if err := c.doJSON("POST", versionPath(noteID, versionID)+"/restore", nil, &note); err != nil {
+		return nil, fmt.Errorf("restoring version %d of note %d: %w", versionID, noteID, err)
+	}
+	return &note, nil
+}
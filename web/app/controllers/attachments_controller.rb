@@ -3,6 +3,14 @@ class AttachmentsController < ApplicationController
 
   MAX_FILE_SIZE = 25.megabytes
 
+  # Stable URL for an attachment, for links and images in note bodies: blob
+  # URLs change with the signing key, attachment IDs do not.
+  def show
+    @note = current_user.accessible_notes.find(params[:note_id])
+    attachment = @note.attachments.find(params[:id])
+    redirect_to rails_blob_path(attachment, disposition: :inline)
+  end
+
   def create
     @note = current_user.accessible_notes.find(params[:note_id])
     unless @note.editable_by?(current_user)
@@ -45,7 +45,7 @@ Rails.application.routes.draw do
         post :restore
       end
     end
-    resources :attachments, only: [ :create, :destroy ]
+    resources :attachments, only: [ :show, :create, :destroy ]
   end
 
   resources :tags, except: [ :show, :new ]
@@ -0,0 +1,27 @@
+require "rails_helper"
+
+RSpec.describe "Attachments", type: :request do
+  let(:user) { create(:user, :password_user) }
+  let(:note) { create(:note, user: user) }
+
+  before do
+    note.attachments.attach(io: StringIO.new("hello"), filename: "hello.txt", content_type: "text/plain")
+    post password_login_path, params: { email: user.email, password: "password" }
+  end
+
+  describe "GET /notes/:note_id/attachments/:id" do
+    it "redirects to the attachment's blob" do
+      attachment = note.attachments.first
+      get note_attachment_path(note, attachment)
+      expect(response).to have_http_status(:redirect)
+      expect(response.location).to include("/rails/active_storage/blobs/")
+    end
+
+    it "returns 404 for another user's note" do
+      other = create(:note)
+      other.attachments.attach(io: StringIO.new("secret"), filename: "secret.txt", content_type: "text/plain")
+      get note_attachment_path(other, other.attachments.first)
+      expect(response).to have_http_status(:not_found)
+    end
+  end
+end